@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Annotation is something a brain noted while answering a request, such as
+// the food it is going for. Annotations are saved in game recordings and
+// shown over the board in the game viewer.
+type Annotation struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+
+	// Cells are the squares of the board the annotation is about.
+	Cells []Coord `json:"cells,omitempty"`
+}
+
+type annotationsKey struct{}
+
+type annotations struct {
+	lock sync.Mutex
+	list []Annotation
+}
+
+// WithAnnotations makes a context that collects the annotations brains make
+// with it. Collected returns everything annotated so far.
+func WithAnnotations(ctx context.Context) (_ context.Context, collected func() []Annotation) {
+	a := &annotations{}
+	return context.WithValue(ctx, annotationsKey{}, a), func() []Annotation {
+		a.lock.Lock()
+		defer a.lock.Unlock()
+
+		return append([]Annotation(nil), a.list...)
+	}
+}
+
+// Annotate notes something about the request being answered with ctx. It
+// does nothing if nobody is collecting annotations, so brains can call it
+// freely.
+func Annotate(ctx context.Context, key string, value interface{}, cells ...Coord) {
+	a, ok := ctx.Value(annotationsKey{}).(*annotations)
+	if !ok {
+		return
+	}
+
+	an := Annotation{Key: key, Cells: cells}
+	if value != nil {
+		an.Value = fmt.Sprint(value)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.list = append(a.list, an)
+}
@@ -1,14 +1,23 @@
+// Package api is the Battlesnake v1 API and the board model shared by every
+// snake in this repo.
+//
+// Coordinates follow the v1 API: the origin (0,0) is the bottom-left corner
+// of the board, X grows to the right and Y grows upwards, so moving "up" adds
+// one to Y. Anything that stores per-cell data should use Grid so it is
+// indexed the same way everywhere.
 package api
 
 import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"within.website/ln"
 )
 
-// Coord is an X,Y coordinate pair.
+// Coord is an X,Y coordinate pair. See the package documentation for the
+// coordinate system.
 type Coord struct {
 	X int `json:"x"`
 	Y int `json:"y"`
@@ -18,20 +27,32 @@ func (l Coord) String() string {
 	return fmt.Sprintf("(%d,%d)", l.X, l.Y)
 }
 
-// Dir computes the net immediate direction from point l to point r
-func (l Coord) Dir(r Coord) string {
+// Dir computes the net immediate direction from point l to point r. It
+// returns NoDirection if the two points are the same.
+func (l Coord) Dir(r Coord) Direction {
 	switch {
 	case l.X < r.X:
-		return "right"
+		return Right
 	case l.X > r.X:
-		return "left"
+		return Left
 	case l.Y < r.Y:
-		return "up"
+		return Up
 	case l.Y > r.Y:
-		return "down"
+		return Down
 	}
 
-	return "how"
+	return NoDirection
+}
+
+// Neighbors returns the four coordinates adjacent to this one, in the order
+// of Directions. Some of them may be outside the board.
+func (l Coord) Neighbors() [4]Coord {
+	var result [4]Coord
+	for i, d := range Directions {
+		result[i] = d.Apply(l)
+	}
+
+	return result
 }
 
 // Eq checks if one Coord equals another.
@@ -39,6 +60,7 @@ func (l Coord) Eq(r Coord) bool {
 	return l.X == r.X && l.Y == r.Y
 }
 
+// Left returns the Coord to the left of (-X) this one.
 func (l Coord) Left() Coord {
 	return Coord{
 		X: l.X - 1,
@@ -46,6 +68,7 @@ func (l Coord) Left() Coord {
 	}
 }
 
+// Right returns the Coord to the right of (+X) this one.
 func (l Coord) Right() Coord {
 	return Coord{
 		X: l.X + 1,
@@ -53,6 +76,7 @@ func (l Coord) Right() Coord {
 	}
 }
 
+// Up returns the Coord above (+Y) this one.
 func (l Coord) Up() Coord {
 	return Coord{
 		X: l.X,
@@ -60,6 +84,7 @@ func (l Coord) Up() Coord {
 	}
 }
 
+// Down returns the Coord below (-Y) this one.
 func (l Coord) Down() Coord {
 	return Coord{
 		X: l.X,
@@ -73,14 +98,44 @@ type Snake struct {
 	Name   string  `json:"name"`
 	Health int     `json:"health"`
 	Body   []Coord `json:"body"`
+	Head   Coord   `json:"head"`
+	Length int     `json:"length"`
+
+	// Latency is the number of milliseconds the snake took to answer the
+	// previous move request, as reported by the game engine. It is a string
+	// on the wire and is "0" when the snake timed out.
+	Latency string `json:"latency"`
+	Shout   string `json:"shout"`
+	Squad   string `json:"squad"`
+
+	Customizations Customizations `json:"customizations"`
+}
+
+// LatencyDuration parses Latency. It returns false if the engine did not
+// report a usable latency.
+func (s Snake) LatencyDuration() (time.Duration, bool) {
+	var ms int
+	if _, err := fmt.Sscan(s.Latency, &ms); err != nil || ms <= 0 {
+		return 0, false
+	}
+
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// Customizations are the cosmetic settings a snake answered its ping with.
+type Customizations struct {
+	Color string `json:"color"`
+	Head  string `json:"head"`
+	Tail  string `json:"tail"`
 }
 
 // Board is the game board.
 type Board struct {
-	Height int     `json:"height"`
-	Width  int     `json:"width"`
-	Food   []Coord `json:"food"`
-	Snakes []Snake `json:"snakes"`
+	Height  int     `json:"height"`
+	Width   int     `json:"width"`
+	Food    []Coord `json:"food"`
+	Hazards []Coord `json:"hazards"`
+	Snakes  []Snake `json:"snakes"`
 }
 
 // Inside checks if a point is inside the board.
@@ -106,12 +161,7 @@ func (b Board) Inside(x Coord) bool {
 // DeadlyAdjacent returns all of the adjacent deadly coordinates from this one.
 func (b Board) DeadlyAdjacent(x Coord) []Coord {
 	var result []Coord
-	for _, place := range []Coord{
-		x.Up(),
-		x.Left(),
-		x.Down(),
-		x.Right(),
-	} {
+	for _, place := range x.Neighbors() {
 		if b.IsDeadly(place) {
 			result = append(result, place)
 		}
@@ -120,6 +170,17 @@ func (b Board) DeadlyAdjacent(x Coord) []Coord {
 	return result
 }
 
+// IsHazard checks if a point is covered by a hazard.
+func (b Board) IsHazard(x Coord) bool {
+	for _, hz := range b.Hazards {
+		if x.Eq(hz) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // IsDeadly checks if a point would kill a snake if it moved into it.
 func (b Board) IsDeadly(x Coord) bool {
 	if !b.Inside(x) {
@@ -137,8 +198,61 @@ func (b Board) IsDeadly(x Coord) bool {
 	return false
 }
 
+// Game is the metadata about the game being played.
 type Game struct {
-	ID string `json:"id"`
+	ID      string  `json:"id"`
+	Ruleset Ruleset `json:"ruleset"`
+	Map     string  `json:"map"`
+	Source  string  `json:"source"`
+
+	// Timeout is the number of milliseconds a snake has to respond to each
+	// move request.
+	Timeout int `json:"timeout"`
+}
+
+// DefaultTimeout is the move timeout used when the engine does not send one.
+const DefaultTimeout = 500 * time.Millisecond
+
+// MoveTimeout is the amount of time the engine gives a snake to respond to a
+// move request.
+func (g Game) MoveTimeout() time.Duration {
+	if g.Timeout <= 0 {
+		return DefaultTimeout
+	}
+
+	return time.Duration(g.Timeout) * time.Millisecond
+}
+
+// Ruleset is the set of rules the game is being played with.
+type Ruleset struct {
+	Name     string          `json:"name"`
+	Version  string          `json:"version"`
+	Settings RulesetSettings `json:"settings"`
+}
+
+// RulesetSettings are the tunable parameters of a ruleset.
+type RulesetSettings struct {
+	FoodSpawnChance     int    `json:"foodSpawnChance"`
+	MinimumFood         int    `json:"minimumFood"`
+	HazardDamagePerTurn int    `json:"hazardDamagePerTurn"`
+	HazardMap           string `json:"hazardMap,omitempty"`
+	HazardMapAuthor     string `json:"hazardMapAuthor,omitempty"`
+
+	Royale RoyaleSettings `json:"royale"`
+	Squad  SquadSettings  `json:"squad"`
+}
+
+// RoyaleSettings are the settings specific to the royale ruleset.
+type RoyaleSettings struct {
+	ShrinkEveryNTurns int `json:"shrinkEveryNTurns"`
+}
+
+// SquadSettings are the settings specific to the squad ruleset.
+type SquadSettings struct {
+	AllowBodyCollisions bool `json:"allowBodyCollisions"`
+	SharedElimination   bool `json:"sharedElimination"`
+	SharedHealth        bool `json:"sharedHealth"`
+	SharedLength        bool `json:"sharedLength"`
 }
 
 type SnakeRequest struct {
@@ -151,15 +265,22 @@ type SnakeRequest struct {
 func (sr SnakeRequest) F() ln.F {
 	return ln.F{
 		"game_id":      sr.Game.ID,
+		"game_ruleset": sr.Game.Ruleset.Name,
+		"game_timeout": sr.Game.Timeout,
 		"turn":         sr.Turn,
 		"food_count":   len(sr.Board.Food),
+		"hazard_count": len(sr.Board.Hazards),
 		"snakes_count": len(sr.Board.Snakes),
 		"my_health":    sr.You.Health,
+		"my_length":    sr.You.Length,
+		"my_latency":   sr.You.Latency,
 	}
 }
 
 type PingResponse struct {
 	APIVersion string `json:"apiversion,omitempty"`
+	Author     string `json:"author,omitempty"`
+	Version    string `json:"version,omitempty"`
 	Color      string `json:"color,omitempty"`
 	HeadType   string `json:"head,omitempty"`
 	TailType   string `json:"tail,omitempty"`
@@ -172,16 +293,46 @@ func (s PingResponse) F() ln.F {
 }
 
 type MoveResponse struct {
-	Move string `json:"move"`
+	Move  Direction `json:"move"`
+	Shout string    `json:"shout,omitempty"`
 }
 
 func (m MoveResponse) F() ln.F {
-	return ln.F{
-		"response_move": m.Move,
+	f := ln.F{
+		"response_move": m.Move.String(),
+	}
+
+	if m.Shout != "" {
+		f["response_shout"] = m.Shout
+	}
+
+	return f
+}
+
+// Normalize fills in the derived fields (Head and Length) of every snake in
+// the request from its body, for engines that do not send them.
+func (sr *SnakeRequest) Normalize() {
+	sr.You.normalize()
+	for i := range sr.Board.Snakes {
+		sr.Board.Snakes[i].normalize()
 	}
 }
 
+func (s *Snake) normalize() {
+	if len(s.Body) == 0 {
+		return
+	}
+
+	s.Head = s.Body[0]
+	s.Length = len(s.Body)
+}
+
 func DecodeSnakeRequest(req *http.Request, decoded *SnakeRequest) error {
 	err := json.NewDecoder(req.Body).Decode(&decoded)
-	return err
+	if err != nil {
+		return err
+	}
+
+	decoded.Normalize()
+	return nil
 }
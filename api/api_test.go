@@ -1,23 +1,32 @@
 package api
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestDirections(t *testing.T) {
-	for _, dir := range []string{"up", "down", "left", "right"} {
-		t.Run(dir, func(t *testing.T) {
+	for _, dir := range Directions {
+		t.Run(dir.String(), func(t *testing.T) {
 			c := Coord{
 				X: 1,
 				Y: 1,
 			}
 			var next Coord
 			switch dir {
-			case "up":
+			case Up:
 				next = c.Up()
-			case "down":
+			case Down:
 				next = c.Down()
-			case "left":
+			case Left:
 				next = c.Left()
-			case "right":
+			case Right:
 				next = c.Right()
 			}
 
@@ -26,6 +35,255 @@ func TestDirections(t *testing.T) {
 				t.Logf("next: %#v", next)
 				t.Errorf("expected to get %s, got: %s", dir, cn)
 			}
+
+			if applied := dir.Apply(c); !applied.Eq(next) {
+				t.Errorf("expected %s.Apply(%s) to be %s, got: %s", dir, c, next, applied)
+			}
+
+			if back := dir.Opposite().Apply(next); !back.Eq(c) {
+				t.Errorf("expected %s to undo %s, got: %s", dir.Opposite(), dir, back)
+			}
 		})
 	}
+
+	if d := (Coord{X: 1, Y: 1}).Dir(Coord{X: 1, Y: 1}); d != NoDirection {
+		t.Errorf("expected no direction between equal coords, got: %s", d)
+	}
+}
+
+func TestNeighbors(t *testing.T) {
+	c := Coord{X: 4, Y: 2}
+	for i, n := range c.Neighbors() {
+		if d := c.Dir(n); d != Directions[i] {
+			t.Errorf("neighbor %d: expected %s, got: %s", i, Directions[i], d)
+		}
+	}
+}
+
+func TestDirectionJSON(t *testing.T) {
+	for _, dir := range Directions {
+		data, err := json.Marshal(MoveResponse{Move: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := `{"move":"` + dir.String() + `"}`
+		if string(data) != want {
+			t.Errorf("wanted %s, got: %s", want, data)
+		}
+
+		var mr MoveResponse
+		if err := json.Unmarshal(data, &mr); err != nil {
+			t.Fatal(err)
+		}
+
+		if mr.Move != dir {
+			t.Errorf("round trip: wanted %s, got: %s", dir, mr.Move)
+		}
+	}
+
+	if _, err := json.Marshal(MoveResponse{}); err == nil {
+		t.Error("marshalling an invalid direction should fail")
+	}
+
+	var mr MoveResponse
+	if err := json.Unmarshal([]byte(`{"move":"how"}`), &mr); err == nil {
+		t.Error("unmarshalling an unknown direction should fail")
+	}
+}
+
+const v1Request = `{
+  "game": {
+    "id": "game-00fe20da-94ad-11ea-bb37",
+    "ruleset": {
+      "name": "royale",
+      "version": "v1.2.3",
+      "settings": {
+        "foodSpawnChance": 25,
+        "minimumFood": 1,
+        "hazardDamagePerTurn": 14,
+        "royale": {"shrinkEveryNTurns": 5},
+        "squad": {
+          "allowBodyCollisions": true,
+          "sharedElimination": true,
+          "sharedHealth": false,
+          "sharedLength": true
+        }
+      }
+    },
+    "map": "standard",
+    "source": "league",
+    "timeout": 600
+  },
+  "turn": 14,
+  "board": {
+    "height": 11,
+    "width": 11,
+    "food": [{"x": 5, "y": 5}],
+    "hazards": [{"x": 0, "y": 0}, {"x": 0, "y": 1}],
+    "snakes": [
+      {
+        "id": "snake-508e96ac-94ad-11ea-bb37",
+        "name": "My Snake",
+        "health": 54,
+        "body": [{"x": 0, "y": 0}, {"x": 1, "y": 0}, {"x": 2, "y": 0}],
+        "latency": "111",
+        "head": {"x": 0, "y": 0},
+        "length": 3,
+        "shout": "why are we shouting??",
+        "squad": "1",
+        "customizations": {"color": "#FF0000", "head": "pixel", "tail": "pixel"}
+      }
+    ]
+  },
+  "you": {
+    "id": "snake-508e96ac-94ad-11ea-bb37",
+    "name": "My Snake",
+    "health": 54,
+    "body": [{"x": 0, "y": 0}, {"x": 1, "y": 0}, {"x": 2, "y": 0}],
+    "latency": "111",
+    "head": {"x": 0, "y": 0},
+    "length": 3,
+    "shout": "why are we shouting??",
+    "squad": "1"
+  }
+}`
+
+func TestDecodeSnakeRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/move", strings.NewReader(v1Request))
+
+	var sr SnakeRequest
+	if err := DecodeSnakeRequest(req, &sr); err != nil {
+		t.Fatal(err)
+	}
+
+	rs := sr.Game.Ruleset
+	switch {
+	case rs.Name != "royale", rs.Version != "v1.2.3":
+		t.Errorf("wrong ruleset: %#v", rs)
+	case rs.Settings.FoodSpawnChance != 25, rs.Settings.MinimumFood != 1, rs.Settings.HazardDamagePerTurn != 14:
+		t.Errorf("wrong ruleset settings: %#v", rs.Settings)
+	case rs.Settings.Royale.ShrinkEveryNTurns != 5:
+		t.Errorf("wrong royale settings: %#v", rs.Settings.Royale)
+	case !rs.Settings.Squad.AllowBodyCollisions, rs.Settings.Squad.SharedHealth:
+		t.Errorf("wrong squad settings: %#v", rs.Settings.Squad)
+	}
+
+	if sr.Game.Map != "standard" || sr.Game.Source != "league" {
+		t.Errorf("wrong game metadata: %#v", sr.Game)
+	}
+
+	if got := sr.Game.MoveTimeout(); got != 600*time.Millisecond {
+		t.Errorf("wanted timeout 600ms, got: %s", got)
+	}
+
+	if len(sr.Board.Hazards) != 2 || !sr.Board.IsHazard(Coord{X: 0, Y: 1}) {
+		t.Errorf("wrong hazards: %v", sr.Board.Hazards)
+	}
+
+	me := sr.Board.Snakes[0]
+	if !me.Head.Eq(Coord{X: 0, Y: 0}) || me.Length != 3 || me.Squad != "1" || me.Shout == "" {
+		t.Errorf("wrong snake: %#v", me)
+	}
+
+	if me.Customizations.Color != "#FF0000" {
+		t.Errorf("wrong customizations: %#v", me.Customizations)
+	}
+
+	if lat, ok := sr.You.LatencyDuration(); !ok || lat != 111*time.Millisecond {
+		t.Errorf("wanted latency 111ms, got: %s (%v)", lat, ok)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	sr := SnakeRequest{
+		You: Snake{
+			Body: []Coord{{X: 3, Y: 4}, {X: 3, Y: 3}},
+		},
+	}
+	sr.Board.Snakes = []Snake{sr.You}
+
+	sr.Normalize()
+
+	for _, sn := range []Snake{sr.You, sr.Board.Snakes[0]} {
+		if !sn.Head.Eq(Coord{X: 3, Y: 4}) || sn.Length != 2 {
+			t.Errorf("snake was not normalized: %#v", sn)
+		}
+	}
+
+	if got := (Game{}).MoveTimeout(); got != DefaultTimeout {
+		t.Errorf("wanted default timeout, got: %s", got)
+	}
+}
+
+func TestGrid(t *testing.T) {
+	// deliberately not square so mixing up X and Y breaks something
+	b := Board{Width: 7, Height: 3}
+	g := b.NewGrid()
+
+	for _, c := range []Coord{{X: 0, Y: 0}, {X: 6, Y: 0}, {X: 0, Y: 2}, {X: 6, Y: 2}, {X: 3, Y: 1}} {
+		if !g.Inside(c) || !b.Inside(c) {
+			t.Errorf("%s should be inside", c)
+		}
+
+		g.Set(c, c.X*10+c.Y)
+		if got := g.At(c); got != c.X*10+c.Y {
+			t.Errorf("%s: wanted %d, got: %d", c, c.X*10+c.Y, got)
+		}
+
+		if back := g.Coord(g.Index(c)); !back.Eq(c) {
+			t.Errorf("%s: index round trip gave %s", c, back)
+		}
+	}
+
+	for _, c := range []Coord{{X: 7, Y: 0}, {X: 0, Y: 3}, {X: 2, Y: 6}, {X: -1, Y: 0}} {
+		if g.Inside(c) || b.Inside(c) {
+			t.Errorf("%s should be outside", c)
+		}
+
+		g.Set(c, 1)
+		if g.At(c) != 0 {
+			t.Errorf("%s: reads outside the grid should be zero", c)
+		}
+	}
+
+	rows := g.Rows()
+	if len(rows) != b.Height || len(rows[0]) != b.Width {
+		t.Fatalf("wanted %dx%d rows, got %dx%d", b.Height, b.Width, len(rows), len(rows[0]))
+	}
+
+	if rows[2][6] != 62 {
+		t.Errorf("rows should be indexed [y][x], got: %v", rows)
+	}
+
+	top := Coord{X: 6, Y: 1}.Up()
+	if !top.Eq(Coord{X: 6, Y: 2}) || !b.Inside(top) || b.Inside(top.Up()) {
+		t.Errorf("up should walk towards the top of the board, got: %s", top)
+	}
+}
+
+type testBrain struct {
+	move Direction
+}
+
+func (testBrain) Ping() (*PingResponse, error)                     { return &PingResponse{APIVersion: "1"}, nil }
+func (testBrain) Start(ctx context.Context, sr SnakeRequest) error { return nil }
+func (testBrain) End(ctx context.Context, sr SnakeRequest) error   { return nil }
+
+func (t testBrain) Move(ctx context.Context, sr SnakeRequest) (*MoveResponse, error) {
+	Annotate(ctx, "going", t.move, t.move.Apply(sr.You.Head))
+	return &MoveResponse{Move: t.move}, nil
+}
+
+func post(t *testing.T, h http.Handler, path string, sr SnakeRequest) *httptest.ResponseRecorder {
+	t.Helper()
+
+	data, err := json.Marshal(sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+	return w
 }
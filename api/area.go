@@ -0,0 +1,185 @@
+package api
+
+// ClearsAt is how many turns until each square of the board is free to move
+// into. Empty squares are 0. A body segment is 1 if it moves out of the way
+// this turn, as a tail does, 2 the turn after, and so on up to the head.
+//
+// It assumes nobody eats in the meantime; a snake that eats keeps its tail
+// where it is for another turn.
+func (b Board) ClearsAt() Grid {
+	g := b.NewGrid()
+	b.clearsInto(g)
+
+	return g
+}
+
+// clearsInto is ClearsAt into a zeroed grid the size of the board.
+func (b Board) clearsInto(g Grid) {
+	for _, sn := range b.Snakes {
+		for i, c := range sn.Body {
+			// a stacked tail is the same square more than once, and it
+			// clears when the last of them does
+			if t := len(sn.Body) - i; t > g.At(c) {
+				g.Set(c, t)
+			}
+		}
+	}
+}
+
+// FloodFill counts the squares reachable from start without going through
+// a snake, stopping once limit have been found. Start itself counts, unless
+// it is a snake.
+func (b Board) FloodFill(start Coord, limit int) int {
+	blocked := b.NewGrid()
+	for _, sn := range b.Snakes {
+		for _, c := range sn.Body {
+			blocked.Set(c, 1<<30)
+		}
+	}
+
+	return reachable(blocked, start, limit)
+}
+
+// Reachable counts the squares a head that moves into start next turn could
+// get to, stopping once limit have been found. Start itself counts, unless
+// the head can't move into it.
+//
+// Unlike FloodFill it knows snakes move: a body segment can be moved into
+// if it will have cleared by the time the head gets there (see ClearsAt),
+// so a snake following its own tail has room.
+func (b Board) Reachable(start Coord, limit int) int {
+	return reachable(b.ClearsAt(), start, limit)
+}
+
+// reachable is a breadth first search from start, which is reached in one
+// turn, through the squares of clears that have cleared by the time they
+// are reached.
+func reachable(clears Grid, start Coord, limit int) int {
+	if !clears.Inside(start) || clears.At(start) > 1 || limit <= 0 {
+		return 0
+	}
+
+	// seen holds the turn each square was reached in
+	seen := NewGrid(clears.Width, clears.Height)
+	seen.Set(start, 1)
+	queue := []Coord{start}
+
+	count := 0
+	for len(queue) > 0 && count < limit {
+		c := queue[0]
+		queue = queue[1:]
+		count++
+
+		turn := seen.At(c) + 1
+		for _, n := range c.Neighbors() {
+			if clears.Inside(n) && seen.At(n) == 0 && clears.At(n) <= turn {
+				seen.Set(n, turn)
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	return count
+}
+
+// HasRoom reports whether a snake of the given length that moves into start
+// next turn has somewhere to go for at least as many turns as it is long,
+// which is about how long it takes to follow its own tail out of a pocket.
+func (b Board) HasRoom(start Coord, length int) bool {
+	return b.Reachable(start, length) >= length
+}
+
+// ArticulationPoints finds the chokepoints of the board: the squares that,
+// if a snake moved into them, would cut the squares that are free next turn
+// into more pieces. Squares free next turn are the empty ones and tails that
+// are about to move (see ClearsAt). They are in the order Grid.Index puts
+// them in.
+func (b Board) ArticulationPoints() []Coord {
+	clears := b.ClearsAt()
+	free := func(c Coord) bool {
+		return clears.Inside(c) && clears.At(c) <= 1
+	}
+
+	// Tarjan's algorithm: a square is a chokepoint if some square found
+	// through it can't get back above it in the depth first search without
+	// going through it. The search is done with a stack so big boards
+	// can't overflow anything.
+	var (
+		order = NewGrid(clears.Width, clears.Height) // when each square was found, from 1
+		low   = NewGrid(clears.Width, clears.Height) // earliest square reachable without the parent
+		cut   = NewGrid(clears.Width, clears.Height)
+		found int
+	)
+
+	type frame struct {
+		c, parent Coord
+		next      int // which neighbor to look at next
+		children  int
+	}
+
+	for i := range clears.Cells {
+		root := clears.Coord(i)
+		if !free(root) || order.At(root) != 0 {
+			continue
+		}
+
+		found++
+		order.Set(root, found)
+		low.Set(root, found)
+		stack := []frame{{c: root, parent: Coord{X: -1, Y: -1}}}
+
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			neighbors := top.c.Neighbors()
+
+			if top.next < len(neighbors) {
+				n := neighbors[top.next]
+				top.next++
+
+				switch {
+				case !free(n) || n.Eq(top.parent):
+				case order.At(n) != 0:
+					if order.At(n) < low.At(top.c) {
+						low.Set(top.c, order.At(n))
+					}
+				default:
+					found++
+					order.Set(n, found)
+					low.Set(n, found)
+					top.children++
+					stack = append(stack, frame{c: n, parent: top.c})
+				}
+
+				continue
+			}
+
+			// done with top, tell its parent what it found
+			done := *top
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				if done.children > 1 {
+					cut.Set(done.c, 1)
+				}
+				continue
+			}
+
+			parent := &stack[len(stack)-1]
+			if low.At(done.c) < low.At(parent.c) {
+				low.Set(parent.c, low.At(done.c))
+			}
+
+			if len(stack) > 1 && low.At(done.c) >= order.At(parent.c) {
+				cut.Set(parent.c, 1)
+			}
+		}
+	}
+
+	var result []Coord
+	for i, v := range cut.Cells {
+		if v != 0 {
+			result = append(result, cut.Coord(i))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArea(t *testing.T) {
+	// a 5x3 board with a wall of snake down x=2 that leaves a gap at the
+	// top, and a snake curled up in the bottom left that can chase its tail
+	me := Snake{ID: "me", Body: []Coord{{X: 0, Y: 1}, {X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}}}
+	wall := Snake{ID: "wall", Body: []Coord{{X: 2, Y: 0}, {X: 2, Y: 1}, {X: 3, Y: 1}, {X: 3, Y: 0}, {X: 4, Y: 0}}}
+	b := Board{Width: 5, Height: 3, Snakes: []Snake{me, wall}}
+
+	clears := b.ClearsAt()
+	for c, want := range map[Coord]int{{X: 0, Y: 1}: 4, {X: 1, Y: 1}: 1, {X: 2, Y: 0}: 5, {X: 4, Y: 0}: 1, {X: 0, Y: 2}: 0} {
+		if got := clears.At(c); got != want {
+			t.Errorf("%s should clear in %d turns, got: %d", c, want, got)
+		}
+	}
+
+	// from (0,2) the top row is open, and the tail at (1,1) will have moved
+	if got := b.FloodFill(Coord{X: 0, Y: 2}, 100); got != 6 {
+		t.Errorf("wanted 6 open squares, got: %d", got)
+	}
+
+	// given time every body moves out of the way
+	if got := b.Reachable(Coord{X: 0, Y: 2}, 100); got != 15 {
+		t.Errorf("wanted the whole board reachable in time, got: %d", got)
+	}
+
+	if got := b.Reachable(Coord{X: 0, Y: 2}, 3); got != 3 {
+		t.Errorf("wanted the count to stop at 3, got: %d", got)
+	}
+
+	if b.Reachable(Coord{X: 2, Y: 1}, 100) != 0 || b.FloodFill(Coord{X: 5, Y: 0}, 100) != 0 {
+		t.Error("bodies and squares off the board shouldn't be reachable")
+	}
+
+	if !b.HasRoom(Coord{X: 0, Y: 2}, 4) || b.HasRoom(Coord{X: 0, Y: 2}, 20) {
+		t.Error("wanted room for a snake of 4 but not one of 20")
+	}
+
+	// the squares free next turn are a line from our tail at (1,1) along
+	// the top row and down to the wall's tail at (4,0), with (0,2) off to
+	// the side
+	want := []Coord{{X: 4, Y: 1}, {X: 1, Y: 2}, {X: 2, Y: 2}, {X: 3, Y: 2}, {X: 4, Y: 2}}
+	if got := b.ArticulationPoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted chokepoints %v, got: %v", want, got)
+	}
+}
+
+// benchmarkBoard is a busy 11x11 board with four snakes.
+func benchmarkBoard() Board {
+	return Board{
+		Width:  11,
+		Height: 11,
+		Food:   []Coord{{X: 5, Y: 5}, {X: 0, Y: 10}, {X: 9, Y: 2}},
+		Snakes: []Snake{
+			{ID: "a", Body: []Coord{{X: 1, Y: 1}, {X: 1, Y: 2}, {X: 1, Y: 3}, {X: 2, Y: 3}, {X: 3, Y: 3}}},
+			{ID: "b", Body: []Coord{{X: 9, Y: 9}, {X: 9, Y: 8}, {X: 8, Y: 8}, {X: 7, Y: 8}}},
+			{ID: "c", Body: []Coord{{X: 1, Y: 9}, {X: 2, Y: 9}, {X: 3, Y: 9}, {X: 4, Y: 9}, {X: 4, Y: 8}, {X: 4, Y: 7}}},
+			{ID: "d", Body: []Coord{{X: 9, Y: 1}, {X: 8, Y: 1}, {X: 7, Y: 1}}},
+		},
+	}
+}
+
+func BenchmarkReachable(b *testing.B) {
+	board := benchmarkBoard()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		board.Reachable(Coord{X: 5, Y: 5}, 121)
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// pingTimeout is how long Ping waits for an answer. AI gives Ping no context,
+// and one snake that never answers shouldn't hold up everything else.
+var pingTimeout = DefaultTimeout
+
+// Client is an AI that lives behind the Battlesnake HTTP API somewhere else,
+// such as a snake mounted by Server in another process.
+type Client struct {
+	// URL is the base URL of the snake, such as http://127.0.0.1:5000/pyra/.
+	URL string
+
+	// HTTP is the client used to make requests. If nil, http.DefaultClient
+	// is used.
+	HTTP *http.Client
+}
+
+func (c Client) client() *http.Client {
+	if c.HTTP == nil {
+		return http.DefaultClient
+	}
+
+	return c.HTTP
+}
+
+func (c Client) url(path string) string {
+	return strings.TrimSuffix(c.URL, "/") + "/" + path
+}
+
+// do makes a request and decodes the response into result if it is not nil.
+func (c Client) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.url(path), &buf)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("api: %s %s: %s", method, c.url(path), resp.Status)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// Ping fetches the snake's metadata, giving up after pingTimeout.
+func (c Client) Ping() (*PingResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	var result PingResponse
+	if err := c.do(ctx, http.MethodGet, "", nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Start tells the snake a game is starting.
+func (c Client) Start(ctx context.Context, sr SnakeRequest) error {
+	return c.do(ctx, http.MethodPost, "start", sr, nil)
+}
+
+// Move asks the snake where it wants to go.
+func (c Client) Move(ctx context.Context, sr SnakeRequest) (*MoveResponse, error) {
+	var result MoveResponse
+	if err := c.do(ctx, http.MethodPost, "move", sr, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// End tells the snake the game is over.
+func (c Client) End(ctx context.Context, sr SnakeRequest) error {
+	return c.do(ctx, http.MethodPost, "end", sr, nil)
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Defaults for the time budget Server gives brains to answer in.
+const (
+	// DefaultMargin is how early answers are due before the engine's
+	// deadline, to absorb jitter.
+	DefaultMargin = 30 * time.Millisecond
+
+	// DefaultNetworkLatency is the round trip to the engine assumed until
+	// one has been measured.
+	DefaultNetworkLatency = 60 * time.Millisecond
+)
+
+// now is the clock answers are timed with. Tests swap it out so they don't
+// depend on how busy the machine is.
+var now = time.Now
+
+// Budget is how long a brain has to answer a request that arrived with the
+// given game timeout, when the round trip to the engine takes network. It
+// never drops below a tenth of the timeout, so a slow network still leaves
+// a brain enough time to do something sensible.
+func Budget(timeout, network, margin time.Duration) time.Duration {
+	budget := timeout - network - margin
+	if min := timeout / 10; budget < min {
+		budget = min
+	}
+
+	return budget
+}
+
+// latencyTracker estimates the round trip to the engine for each game a
+// snake is in. The engine reports the latency of every move it got, which
+// is the round trip plus however long the brain took; the difference is
+// the network.
+type latencyTracker struct {
+	games StateStore
+}
+
+type gameLatency struct {
+	network  time.Duration
+	measured bool
+	took     time.Duration
+}
+
+// network is the estimated round trip for the game sr is from, updated
+// with the latency the engine reported for the last move.
+func (lt *latencyTracker) network(sr SnakeRequest) time.Duration {
+	reported, hasReport := sr.You.LatencyDuration()
+
+	g := lt.games.Update(KeyOf(sr), func(v interface{}, ok bool) interface{} {
+		if !ok {
+			return gameLatency{network: DefaultNetworkLatency}
+		}
+
+		g := v.(gameLatency)
+		if !hasReport || g.took == 0 {
+			return g
+		}
+
+		sample := reported - g.took
+		if sample < 0 {
+			sample = 0
+		}
+
+		// a moving average so one slow round trip doesn't eat into every
+		// move after it, but a slow network is picked up within a few turns
+		if g.measured {
+			sample = (g.network + sample) / 2
+		}
+		g.network, g.measured = sample, true
+
+		return g
+	}).(gameLatency)
+
+	return g.network
+}
+
+// answered notes how long the brain took to answer a request in sr's game.
+func (lt *latencyTracker) answered(sr SnakeRequest, took time.Duration) {
+	lt.games.Update(KeyOf(sr), func(v interface{}, ok bool) interface{} {
+		g, _ := v.(gameLatency)
+		if !ok {
+			g.network = DefaultNetworkLatency
+		}
+		g.took = took
+
+		return g
+	})
+}
+
+// end forgets sr's game.
+func (lt *latencyTracker) end(sr SnakeRequest) {
+	lt.games.Delete(KeyOf(sr))
+}
+
+// TimeLeft is how long there is until ctx's deadline. Without a deadline
+// it is effectively forever.
+func TimeLeft(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 1<<63 - 1
+	}
+
+	return deadline.Sub(now())
+}
+
+// HasTimeFor reports whether something that takes d can still finish
+// before ctx's deadline.
+func HasTimeFor(ctx context.Context, d time.Duration) bool {
+	return ctx.Err() == nil && TimeLeft(ctx) > d
+}
+
+// Trim brings ctx's deadline forward by a tenth of the time left, to leave
+// a little time to answer in for searches that use all they are given.
+// Without a deadline ctx is left as it is.
+func Trim(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithDeadline(ctx, deadline.Add(-TimeLeft(ctx)/10))
+}
+
+// Late reports whether ctx is done or past its deadline. The clock is
+// checked as well as the context, since a busy process can be slow to run
+// the timer that cancels it.
+func Late(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+
+	deadline, ok := ctx.Deadline()
+	return ok && !now().Before(deadline)
+}
+
+// Deepen does iterative deepening: it calls search with depths 1 through
+// maxDepth for as long as the next depth is expected to finish before ctx's
+// deadline, and returns the deepest depth that finished.
+//
+// How long the next depth will take is guessed from how much longer each
+// depth took than the one before it. Search should give up with ctx.Err()
+// when ctx is done; depths that fail don't count, so brains should only
+// keep the result of a search once it returns nil.
+func Deepen(ctx context.Context, maxDepth int, search func(ctx context.Context, depth int) error) (int, error) {
+	var (
+		done  int
+		last  time.Duration
+		ratio = 4.0
+	)
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		if done > 0 && !HasTimeFor(ctx, time.Duration(float64(last)*ratio)) {
+			break
+		}
+
+		start := now()
+		if err := search(ctx, depth); err != nil {
+			if ctx.Err() != nil {
+				// ran out of time mid search, the last depth stands
+				return done, nil
+			}
+
+			return done, err
+		}
+		took := now().Sub(start)
+
+		if last > 0 && took > last {
+			ratio = float64(took) / float64(last)
+		}
+		last, done = took, depth
+	}
+
+	return done, nil
+}
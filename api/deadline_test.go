@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type deadlineBrain struct {
+	testBrain
+	left    []time.Duration
+	advance func(time.Duration)
+}
+
+// Move takes 10ms to answer.
+func (d *deadlineBrain) Move(ctx context.Context, sr SnakeRequest) (*MoveResponse, error) {
+	d.left = append(d.left, TimeLeft(ctx))
+	d.advance(10 * time.Millisecond)
+	return &MoveResponse{Move: Up}, nil
+}
+
+func TestDeadline(t *testing.T) {
+	if got := Budget(500*time.Millisecond, 60*time.Millisecond, 30*time.Millisecond); got != 410*time.Millisecond {
+		t.Errorf("wanted 410ms, got: %s", got)
+	}
+
+	if got := Budget(100*time.Millisecond, 200*time.Millisecond, 30*time.Millisecond); got != 10*time.Millisecond {
+		t.Errorf("a slow network should still leave some time, got: %s", got)
+	}
+
+	var sr SnakeRequest
+	if err := json.Unmarshal([]byte(v1Request), &sr); err != nil {
+		t.Fatal(err)
+	}
+
+	advance, restore := stopClock()
+	defer restore()
+
+	brain := &deadlineBrain{advance: advance}
+	s := &Server{Brain: brain, Name: "test"}
+	for i := 0; i < 2; i++ {
+		if w := post(t, s, "/test/move", sr); w.Code != http.StatusOK {
+			t.Fatalf("move failed: %d %s", w.Code, w.Body)
+		}
+	}
+
+	// the first move has nothing to go on but the default latency, the
+	// second knows the engine saw a 111ms round trip, less the 10ms the
+	// first move took to answer
+	for i, want := range []time.Duration{510 * time.Millisecond, 469 * time.Millisecond} {
+		if got := brain.left[i]; got != want {
+			t.Errorf("move %d: wanted %s left, got: %s", i, want, got)
+		}
+	}
+
+	if TimeLeft(context.Background()) < time.Hour {
+		t.Error("no deadline should mean all the time in the world")
+	}
+}
+
+// stopClock stops the clock answers are timed with until restore is called.
+// Time only passes when advance is called.
+func stopClock() (advance func(time.Duration), restore func()) {
+	at := time.Now()
+	now = func() time.Time { return at }
+
+	return func(d time.Duration) { at = at.Add(d) }, func() { now = time.Now }
+}
+
+func TestDeepen(t *testing.T) {
+	advance, restore := stopClock()
+	defer restore()
+
+	start := now()
+	ctx, cancel := context.WithDeadline(context.Background(), start.Add(200*time.Millisecond))
+	defer cancel()
+
+	best := 0
+	depth, err := Deepen(ctx, 20, func(ctx context.Context, depth int) error {
+		// every depth takes twice as long as the one before it
+		advance(4 * time.Millisecond << uint(depth-1))
+		best = depth
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// depths 1 to 5 take 124ms, and depth 6 would take another 128ms
+	if depth != 5 || depth != best {
+		t.Errorf("wanted depth 5 to be the last to finish, got: %d (searched to %d)", depth, best)
+	}
+
+	if took := now().Sub(start); took > 200*time.Millisecond {
+		t.Errorf("deepening should stop before the deadline, took: %s", took)
+	}
+
+	if depth, _ := Deepen(context.Background(), 3, func(context.Context, int) error { return nil }); depth != 3 {
+		t.Errorf("without a deadline every depth should be searched, got: %d", depth)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	advance, restore := stopClock()
+	defer restore()
+
+	parent, cancel := context.WithDeadline(context.Background(), now().Add(100*time.Millisecond))
+	defer cancel()
+
+	ctx, cancel := Trim(parent)
+	defer cancel()
+
+	if left := TimeLeft(ctx); left != 90*time.Millisecond {
+		t.Errorf("wanted 90ms left, got: %s", left)
+	}
+
+	// the timers run off the real clock, so only ours has passed the
+	// deadline
+	advance(90 * time.Millisecond)
+	if !Late(ctx) || Late(parent) {
+		t.Error("only the trimmed context should be late")
+	}
+
+	ctx, cancel = Trim(context.Background())
+	if _, ok := ctx.Deadline(); ok || Late(ctx) {
+		t.Error("no deadline should stay no deadline")
+	}
+
+	cancel()
+	if !Late(ctx) {
+		t.Error("a cancelled context should be late")
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import "fmt"
+
+// Direction is one of the four moves a snake can make.
+//
+// The zero value is not a valid direction and cannot be marshalled, so a
+// brain that forgets to pick a move gets an error instead of silently
+// sending garbage to the game engine.
+type Direction int
+
+// Directions a snake can move in.
+const (
+	NoDirection Direction = iota
+	Up
+	Down
+	Left
+	Right
+)
+
+// Directions is every valid direction, in the order brains should try them
+// unless they have a reason not to.
+var Directions = [...]Direction{Up, Down, Left, Right}
+
+var directionNames = [...]string{
+	NoDirection: "",
+	Up:          "up",
+	Down:        "down",
+	Left:        "left",
+	Right:       "right",
+}
+
+// ParseDirection converts the wire name of a direction into a Direction.
+func ParseDirection(s string) (Direction, error) {
+	for _, d := range Directions {
+		if directionNames[d] == s {
+			return d, nil
+		}
+	}
+
+	return NoDirection, fmt.Errorf("api: unknown direction %q", s)
+}
+
+// Valid checks if d is one of the four directions.
+func (d Direction) Valid() bool {
+	return d >= Up && d <= Right
+}
+
+func (d Direction) String() string {
+	if !d.Valid() {
+		return fmt.Sprintf("Direction(%d)", int(d))
+	}
+
+	return directionNames[d]
+}
+
+// Apply returns the Coord one step from c in this direction.
+func (d Direction) Apply(c Coord) Coord {
+	switch d {
+	case Up:
+		return c.Up()
+	case Down:
+		return c.Down()
+	case Left:
+		return c.Left()
+	case Right:
+		return c.Right()
+	}
+
+	return c
+}
+
+// Opposite returns the direction that undoes this one.
+func (d Direction) Opposite() Direction {
+	switch d {
+	case Up:
+		return Down
+	case Down:
+		return Up
+	case Left:
+		return Right
+	case Right:
+		return Left
+	}
+
+	return NoDirection
+}
+
+// MarshalText encodes the direction as its wire name.
+func (d Direction) MarshalText() ([]byte, error) {
+	if !d.Valid() {
+		return nil, fmt.Errorf("api: can't marshal invalid direction %d", int(d))
+	}
+
+	return []byte(directionNames[d]), nil
+}
+
+// UnmarshalText decodes a direction from its wire name.
+func (d *Direction) UnmarshalText(data []byte) error {
+	dir, err := ParseDirection(string(data))
+	if err != nil {
+		return err
+	}
+
+	*d = dir
+	return nil
+}
@@ -0,0 +1,108 @@
+package api
+
+import "sync"
+
+// Grid is a Width by Height array of ints addressed by Coord. It exists so
+// that per-cell data is always indexed the same way: cell (x,y) lives in row
+// y, column x, with row 0 at the bottom of the board.
+type Grid struct {
+	Width  int
+	Height int
+	Cells  []int
+}
+
+// NewGrid makes a zeroed grid.
+func NewGrid(width, height int) Grid {
+	return Grid{
+		Width:  width,
+		Height: height,
+		Cells:  make([]int, width*height),
+	}
+}
+
+// NewGrid makes a zeroed grid the size of the board.
+func (b Board) NewGrid() Grid {
+	return NewGrid(b.Width, b.Height)
+}
+
+// Inside checks if a point is inside the grid.
+func (g Grid) Inside(c Coord) bool {
+	return c.X >= 0 && c.Y >= 0 && c.X < g.Width && c.Y < g.Height
+}
+
+// Index is the offset of c in Cells.
+func (g Grid) Index(c Coord) int {
+	return c.Y*g.Width + c.X
+}
+
+// Coord is the inverse of Index.
+func (g Grid) Coord(i int) Coord {
+	return Coord{
+		X: i % g.Width,
+		Y: i / g.Width,
+	}
+}
+
+// At returns the value at c. Points outside the grid read as zero.
+func (g Grid) At(c Coord) int {
+	if !g.Inside(c) {
+		return 0
+	}
+
+	return g.Cells[g.Index(c)]
+}
+
+// Set sets the value at c. Points outside the grid are ignored.
+func (g Grid) Set(c Coord, v int) {
+	if !g.Inside(c) {
+		return
+	}
+
+	g.Cells[g.Index(c)] = v
+}
+
+// Fill sets every cell to v.
+func (g Grid) Fill(v int) {
+	for i := range g.Cells {
+		g.Cells[i] = v
+	}
+}
+
+// Rows returns the grid as a slice of rows, indexed [y][x]. This is the
+// layout goeasystar expects. The rows share storage with the grid.
+func (g Grid) Rows() [][]int {
+	rows := make([][]int, g.Height)
+	for y := range rows {
+		rows[y] = g.Cells[y*g.Width : (y+1)*g.Width]
+	}
+
+	return rows
+}
+
+var neighborTables sync.Map // [2]int -> [][4]int
+
+// NeighborTable is, for every square of a width by height board in Index
+// order, the square one step away in each direction by Direction-1, or -1 off
+// the board. Tables are shared by every board the same size, so they must not
+// be changed.
+func NeighborTable(width, height int) [][4]int {
+	key := [2]int{width, height}
+	if t, ok := neighborTables.Load(key); ok {
+		return t.([][4]int)
+	}
+
+	g := Grid{Width: width, Height: height}
+	table := make([][4]int, width*height)
+	for i := range table {
+		c := g.Coord(i)
+		for _, dir := range Directions {
+			table[i][dir-1] = -1
+			if n := dir.Apply(c); g.Inside(n) {
+				table[i][dir-1] = g.Index(n)
+			}
+		}
+	}
+
+	actual, _ := neighborTables.LoadOrStore(key, table)
+	return actual.([][4]int)
+}
@@ -0,0 +1,470 @@
+package api
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"within.website/ln"
+)
+
+var (
+	recordsWritten = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "recorder_records_written",
+		Help: "The number of requests written to game recordings",
+	}, []string{"brain"})
+
+	recordingsOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "recorder_recordings_open",
+		Help: "The number of game recordings currently open",
+	}, []string{"brain"})
+
+	recordingsPruned = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "recorder_recordings_pruned",
+		Help: "The number of game recordings deleted by retention limits",
+	}, []string{"brain"})
+)
+
+// Kinds of requests a snake answers.
+const (
+	KindStart = "start"
+	KindMove  = "move"
+	KindEnd   = "end"
+)
+
+// RecordingExt is the file extension of game recordings.
+const RecordingExt = ".jsonl.gz"
+
+// Record is one request a snake answered, as stored in a game recording.
+type Record struct {
+	Time    time.Time    `json:"time"`
+	Snake   string       `json:"snake"`
+	Kind    string       `json:"kind"`
+	Request SnakeRequest `json:"request"`
+
+	// Response is what the brain answered a move request with. It is nil
+	// if the brain failed to answer.
+	Response *MoveResponse `json:"response,omitempty"`
+
+	// Fallback is what the engine was sent instead when the brain failed.
+	Fallback *MoveResponse `json:"fallback,omitempty"`
+
+	// Latency is how long the brain took to answer.
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+
+	// Annotations are what the brain noted while answering.
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+// Recorder writes every request a snake answers to a gzipped JSON lines file
+// per game, so games can be looked at and replayed after the fact. The files
+// live in Dir/<snake>/<game id>_<snake id>.jsonl.gz.
+//
+// A Recorder is safe for concurrent use.
+type Recorder struct {
+	Dir string
+
+	// MaxGames is the most recordings to keep per snake. The oldest are
+	// deleted first. Zero means no limit.
+	MaxGames int
+
+	// MaxAge is how long to keep recordings for. Zero means forever.
+	MaxAge time.Duration
+
+	// IdleTimeout closes the recording of a game that hasn't had a request
+	// in this long, for when the end request never comes. If zero, ten
+	// minutes is used.
+	IdleTimeout time.Duration
+
+	// lock guards games and lastSweep. Each recording has its own lock
+	// for writing, so games don't wait on each other's disks.
+	lock      sync.Mutex
+	games     map[string]*recording
+	lastSweep time.Time
+}
+
+type recording struct {
+	lock   sync.Mutex
+	path   string
+	snake  string
+	fout   *os.File
+	gz     *gzip.Writer
+	closed bool
+
+	// lastUsed is guarded by the recorder's lock.
+	lastUsed time.Time
+}
+
+// write appends rec to the recording, opening its file if need be. The
+// caller holds rec.lock.
+func (g *recording) write(rec Record) error {
+	if g.gz == nil {
+		if err := os.MkdirAll(filepath.Dir(g.path), 0755); err != nil {
+			return err
+		}
+
+		// games that were closed while idle get a new gzip stream appended,
+		// which gzip readers treat as part of the same file
+		fout, err := os.OpenFile(g.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+
+		g.fout, g.gz = fout, gzip.NewWriter(fout)
+		recordingsOpen.With(prometheus.Labels{"brain": g.snake}).Inc()
+	}
+
+	if err := json.NewEncoder(g.gz).Encode(rec); err != nil {
+		return err
+	}
+
+	// flush so a crash loses at most the request being written
+	return g.gz.Flush()
+}
+
+// close closes the recording's file, if it was ever opened.
+func (g *recording) close() error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.closed = true
+	if g.gz == nil {
+		return nil
+	}
+
+	err := g.gz.Close()
+	if cerr := g.fout.Close(); err == nil {
+		err = cerr
+	}
+	g.fout, g.gz = nil, nil
+
+	recordingsOpen.With(prometheus.Labels{"brain": g.snake}).Dec()
+	return err
+}
+
+// RecordingPath is where the recording for a snake in a game lives, relative
+// to the recorder's directory.
+func RecordingPath(snake string, sr SnakeRequest) string {
+	return filepath.Join(safeName(snake), safeName(sr.Game.ID+"_"+sr.You.ID)+RecordingExt)
+}
+
+// safeName keeps names from the network from escaping the recording
+// directory.
+func safeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', 0:
+			return '_'
+		}
+		return r
+	}, s)
+
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+
+	return s
+}
+
+// Record writes rec to its game's recording. The recording is closed and old
+// recordings are pruned once the game ends.
+func (r *Recorder) Record(rec Record) error {
+	now := time.Now()
+	path := filepath.Join(r.Dir, RecordingPath(rec.Snake, rec.Request))
+
+	// a recording can be closed as idle between being looked up and being
+	// written to, in which case it is opened again
+	var g *recording
+	for {
+		g = r.recording(path, rec.Snake, now)
+		g.lock.Lock()
+		if !g.closed {
+			break
+		}
+		g.lock.Unlock()
+	}
+
+	err := g.write(rec)
+	g.lock.Unlock()
+	recordsWritten.With(prometheus.Labels{"brain": rec.Snake}).Inc()
+
+	if rec.Kind == KindEnd {
+		r.lock.Lock()
+		if r.games[path] == g {
+			delete(r.games, path)
+		}
+		r.lock.Unlock()
+
+		if cerr := g.close(); err == nil {
+			err = cerr
+		}
+
+		if perr := r.prune(filepath.Join(r.Dir, safeName(rec.Snake)), rec.Snake); err == nil {
+			err = perr
+		}
+	}
+
+	if serr := r.closeIdle(now); err == nil {
+		err = serr
+	}
+
+	return err
+}
+
+// recording gets the open recording at path, making it if there isn't one.
+func (r *Recorder) recording(path, snake string, now time.Time) *recording {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.games == nil {
+		r.games = map[string]*recording{}
+	}
+
+	g, ok := r.games[path]
+	if !ok {
+		g = &recording{path: path, snake: snake}
+		r.games[path] = g
+	}
+	g.lastUsed = now
+
+	return g
+}
+
+// save records a request, logging any failure. It does nothing on a nil
+// Recorder.
+func (r *Recorder) save(ctx context.Context, snake, kind string, sr SnakeRequest, mr, fallback *MoveResponse, latency time.Duration, notes []Annotation, err error) {
+	if r == nil {
+		return
+	}
+
+	rec := Record{
+		Time:        time.Now(),
+		Snake:       snake,
+		Kind:        kind,
+		Request:     sr,
+		Response:    mr,
+		Fallback:    fallback,
+		Latency:     latency,
+		Annotations: notes,
+	}
+
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	if err := r.Record(rec); err != nil {
+		ln.Error(ctx, err, ln.F{"action": "recording_request"})
+	}
+}
+
+// Wrap records every request ai answers as snake, the same way Server does.
+// It is for brains that aren't behind a Server, such as in local games.
+func (r *Recorder) Wrap(snake string, ai AI) AI {
+	return recordedAI{AI: ai, snake: snake, r: r}
+}
+
+type recordedAI struct {
+	AI
+	snake string
+	r     *Recorder
+}
+
+func (ra recordedAI) Start(ctx context.Context, sr SnakeRequest) error {
+	ctx, annotations := WithAnnotations(ctx)
+	start := time.Now()
+	err := ra.AI.Start(ctx, sr)
+	ra.r.save(ctx, ra.snake, KindStart, sr, nil, nil, time.Since(start), annotations(), err)
+
+	return err
+}
+
+func (ra recordedAI) Move(ctx context.Context, sr SnakeRequest) (*MoveResponse, error) {
+	ctx, annotations := WithAnnotations(ctx)
+	start := time.Now()
+	mr, err := ra.AI.Move(ctx, sr)
+	ra.r.save(ctx, ra.snake, KindMove, sr, mr, nil, time.Since(start), annotations(), err)
+
+	return mr, err
+}
+
+func (ra recordedAI) End(ctx context.Context, sr SnakeRequest) error {
+	ctx, annotations := WithAnnotations(ctx)
+	start := time.Now()
+	err := ra.AI.End(ctx, sr)
+	ra.r.save(ctx, ra.snake, KindEnd, sr, nil, nil, time.Since(start), annotations(), err)
+
+	return err
+}
+
+// closeIdle closes every recording that hasn't been used in a while, at
+// most once a minute.
+func (r *Recorder) closeIdle(now time.Time) error {
+	timeout := r.IdleTimeout
+	if timeout == 0 {
+		timeout = 10 * time.Minute
+	}
+
+	var idle []*recording
+	r.lock.Lock()
+	if now.Sub(r.lastSweep) > time.Minute {
+		r.lastSweep = now
+		for path, g := range r.games {
+			if now.Sub(g.lastUsed) >= timeout {
+				delete(r.games, path)
+				idle = append(idle, g)
+			}
+		}
+	}
+	r.lock.Unlock()
+
+	var err error
+	for _, g := range idle {
+		if cerr := g.close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// prune deletes the recordings in dir that are past the retention limits.
+// Open recordings are never deleted.
+func (r *Recorder) prune(dir, snake string) error {
+	if r.MaxGames == 0 && r.MaxAge == 0 {
+		return nil
+	}
+
+	infos, err := listRecordings(dir)
+	if err != nil {
+		return err
+	}
+
+	// newest first
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+
+	kept := 0
+	for _, fi := range infos {
+		path := filepath.Join(dir, fi.Name())
+		if r.isOpen(path) {
+			continue
+		}
+
+		tooOld := r.MaxAge != 0 && time.Since(fi.ModTime()) > r.MaxAge
+		tooMany := r.MaxGames != 0 && kept >= r.MaxGames
+		if !tooOld && !tooMany {
+			kept++
+			continue
+		}
+
+		switch rerr := os.Remove(path); {
+		case rerr == nil:
+			recordingsPruned.With(prometheus.Labels{"brain": snake}).Inc()
+		case os.IsNotExist(rerr):
+			// another game that ended at the same time got to it first
+		case err == nil:
+			err = rerr
+		}
+	}
+
+	return err
+}
+
+func (r *Recorder) isOpen(path string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	_, ok := r.games[path]
+	return ok
+}
+
+func listRecordings(dir string) ([]os.FileInfo, error) {
+	fin, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer fin.Close()
+
+	infos, err := fin.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := infos[:0]
+	for _, fi := range infos {
+		if !fi.IsDir() && strings.HasSuffix(fi.Name(), RecordingExt) {
+			result = append(result, fi)
+		}
+	}
+
+	return result, nil
+}
+
+// Close closes every open recording.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	games := r.games
+	r.games = nil
+	r.lock.Unlock()
+
+	var err error
+	for _, g := range games {
+		if cerr := g.close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// ReadRecords reads every record out of a game recording.
+func ReadRecords(rdr io.Reader) ([]Record, error) {
+	gz, err := gzip.NewReader(rdr)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var result []Record
+	dec := json.NewDecoder(bufio.NewReader(gz))
+	for {
+		var rec Record
+		err := dec.Decode(&rec)
+		switch {
+		case err == io.EOF:
+			return result, nil
+		case errors.Is(err, io.ErrUnexpectedEOF) && len(result) > 0:
+			// the recording was cut off while being written, keep what
+			// made it to disk
+			return result, nil
+		case err != nil:
+			return result, err
+		}
+
+		result = append(result, rec)
+	}
+}
+
+// OpenRecording reads every record out of the game recording at path.
+func OpenRecording(path string) ([]Record, error) {
+	fin, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fin.Close()
+
+	return ReadRecords(fin)
+}
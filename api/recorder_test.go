@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bsnk-recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rec := &Recorder{Dir: dir, MaxGames: 2}
+	defer rec.Close()
+
+	s := &Server{Brain: testBrain{move: Left}, Name: "test", Recorder: rec}
+
+	var sr SnakeRequest
+	if err := json.Unmarshal([]byte(v1Request), &sr); err != nil {
+		t.Fatal(err)
+	}
+
+	for game := 0; game < 3; game++ {
+		sr.Game.ID = fmt.Sprintf("game-%d", game)
+		sr.Turn = 0
+		post(t, s, "/test/start", sr)
+		for sr.Turn = 0; sr.Turn < 3; sr.Turn++ {
+			w := post(t, s, "/test/move", sr)
+			if w.Code != http.StatusOK {
+				t.Fatalf("move failed: %d %s", w.Code, w.Body)
+			}
+
+			// the answer goes out before it's recorded
+			if !w.Flushed || w.Header().Get("Content-Length") != fmt.Sprint(w.Body.Len()) {
+				t.Errorf("wanted the answer flushed with its length, got flushed %v and headers %v", w.Flushed, w.Header())
+			}
+		}
+		post(t, s, "/test/end", sr)
+
+		// make sure the games have different modification times
+		path := filepath.Join(dir, RecordingPath("test", sr))
+		when := time.Now().Add(time.Duration(game-10) * time.Second)
+		if err := os.Chtimes(path, when, when); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sr.Game.ID = "game-2"
+	records, err := OpenRecording(filepath.Join(dir, RecordingPath("test", sr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kinds []string
+	for _, r := range records {
+		kinds = append(kinds, r.Kind)
+		if r.Snake != "test" || r.Request.Game.ID != "game-2" {
+			t.Errorf("bad record: %#v", r)
+		}
+
+		if r.Kind == KindMove && (r.Response == nil || r.Response.Move != Left) {
+			t.Errorf("move records should have the response: %#v", r.Response)
+		}
+
+		if r.Kind == KindMove && (len(r.Annotations) != 1 || r.Annotations[0].Value != "left" || r.Annotations[0].Cells[0] != r.Request.You.Head.Left()) {
+			t.Errorf("move records should have the annotations: %#v", r.Annotations)
+		}
+	}
+
+	want := []string{KindStart, KindMove, KindMove, KindMove, KindEnd}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("wanted %v, got: %v", want, kinds)
+	}
+
+	infos, err := ioutil.ReadDir(filepath.Join(dir, "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(infos) != 2 {
+		t.Errorf("only 2 games should be kept, got: %d", len(infos))
+	}
+
+	sr.Game.ID = "game-0"
+	if _, err := os.Stat(filepath.Join(dir, RecordingPath("test", sr))); !os.IsNotExist(err) {
+		t.Errorf("the oldest game should have been pruned: %v", err)
+	}
+}
+
+func TestRecorderWrap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bsnk-recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rec := &Recorder{Dir: dir}
+	defer rec.Close()
+
+	var sr SnakeRequest
+	if err := json.Unmarshal([]byte(v1Request), &sr); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	ai := rec.Wrap("local", testBrain{move: Up})
+	ai.Start(ctx, sr)
+	if mr, err := ai.Move(ctx, sr); err != nil || mr.Move != Up {
+		t.Fatalf("wrapped brain should answer the same: %v %v", mr, err)
+	}
+	ai.End(ctx, sr)
+
+	records, err := OpenRecording(filepath.Join(dir, RecordingPath("local", sr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 3 || records[1].Kind != KindMove || records[1].Response.Move != Up || len(records[1].Annotations) != 1 {
+		t.Errorf("wrong records: %#v", records)
+	}
+
+	// annotating without anyone collecting is fine
+	Annotate(ctx, "nobody", "listening")
+}
+
+func TestRecordingPath(t *testing.T) {
+	sr := SnakeRequest{Game: Game{ID: "../../etc/passwd"}, You: Snake{ID: "me"}}
+	for _, snake := range []string{"x", "../x", ".."} {
+		p := RecordingPath(snake, sr)
+		if dir := filepath.Dir(p); dir == ".." || strings.ContainsRune(dir, filepath.Separator) {
+			t.Errorf("recording paths should stay in the snake's dir: %s", p)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// Diff is a turn where a brain didn't do what a recording says it did.
+type Diff struct {
+	Record Record `json:"record"`
+
+	// Move is what the brain picked this time. It is NoDirection if the
+	// brain failed.
+	Move  Direction `json:"move"`
+	Error string    `json:"error,omitempty"`
+}
+
+// Recorded is the move in the recording, or NoDirection if the recorded
+// brain failed.
+func (d Diff) Recorded() Direction {
+	if d.Record.Response == nil {
+		return NoDirection
+	}
+
+	return d.Record.Response.Move
+}
+
+// Replay feeds every recorded request to ai in order and returns every move
+// request where it picked a different move than the recording, or where
+// exactly one of them failed.
+func Replay(ctx context.Context, ai AI, records []Record) ([]Diff, error) {
+	var result []Diff
+	for _, rec := range records {
+		switch rec.Kind {
+		case KindStart:
+			if err := ai.Start(ctx, rec.Request); err != nil {
+				return result, fmt.Errorf("api: replaying start of %s: %w", rec.Request.Game.ID, err)
+			}
+		case KindEnd:
+			if err := ai.End(ctx, rec.Request); err != nil {
+				return result, fmt.Errorf("api: replaying end of %s: %w", rec.Request.Game.ID, err)
+			}
+		case KindMove:
+			d := Diff{Record: rec}
+			mr, err := CallMove(ctx, ai, rec.Request)
+			switch {
+			case err != nil:
+				d.Error = err.Error()
+			default:
+				d.Move = mr.Move
+			}
+
+			if d.Move != d.Recorded() {
+				result = append(result, d)
+			}
+		}
+	}
+
+	return result, nil
+}
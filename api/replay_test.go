@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+type recordingBrain struct {
+	testBrain
+	started, ended int
+}
+
+func (r *recordingBrain) Start(ctx context.Context, sr SnakeRequest) error {
+	r.started++
+	return nil
+}
+
+func (r *recordingBrain) End(ctx context.Context, sr SnakeRequest) error {
+	r.ended++
+	return nil
+}
+
+func TestReplay(t *testing.T) {
+	var sr SnakeRequest
+	if err := json.Unmarshal([]byte(v1Request), &sr); err != nil {
+		t.Fatal(err)
+	}
+
+	records := []Record{{Kind: KindStart, Request: sr}}
+	for turn, dir := range []Direction{Left, Left, Up, Left} {
+		sr.Turn = turn
+		records = append(records, Record{Kind: KindMove, Request: sr, Response: &MoveResponse{Move: dir}})
+	}
+	sr.Turn = 4
+	records = append(records,
+		Record{Kind: KindMove, Request: sr, Error: "it broke"},
+		Record{Kind: KindEnd, Request: sr},
+	)
+
+	brain := &recordingBrain{testBrain: testBrain{move: Left}}
+	diffs, err := Replay(context.Background(), brain, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if brain.started != 1 || brain.ended != 1 {
+		t.Errorf("the brain should be started and ended once: %d %d", brain.started, brain.ended)
+	}
+
+	if len(diffs) != 2 {
+		t.Fatalf("wanted 2 diffs, got: %#v", diffs)
+	}
+
+	if d := diffs[0]; d.Record.Request.Turn != 2 || d.Recorded() != Up || d.Move != Left {
+		t.Errorf("wrong diff: turn %d, %s -> %s", d.Record.Request.Turn, d.Recorded(), d.Move)
+	}
+
+	if d := diffs[1]; d.Record.Request.Turn != 4 || d.Recorded() != NoDirection || d.Move != Left {
+		t.Errorf("failed moves should be diffs: turn %d, %s -> %s", d.Record.Request.Turn, d.Recorded(), d.Move)
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrInvalidMove is returned by CallMove when a brain answers with something
+// that isn't a move.
+var ErrInvalidMove = errors.New("api: brain picked an invalid move")
+
+// PanicError is a panic in a brain, caught by Guard.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("api: brain panicked: %v", p.Value)
+}
+
+// Guard calls fn, turning a panic into a *PanicError.
+func Guard(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	return fn()
+}
+
+// CallMove asks ai for a move without trusting it: panics come back as a
+// *PanicError, moves that aren't moves as ErrInvalidMove, and if ctx is done
+// before ai answers CallMove gives up with ctx's error. A brain that never
+// answers is left running in the background; see AI for what that means
+// for brains that keep state.
+func CallMove(ctx context.Context, ai AI, sr SnakeRequest) (*MoveResponse, error) {
+	type answer struct {
+		mr  *MoveResponse
+		err error
+	}
+
+	// buffered so a brain that answers too late doesn't leak its goroutine
+	done := make(chan answer, 1)
+	go func() {
+		var a answer
+		a.err = Guard(func() error {
+			var err error
+			a.mr, err = ai.Move(ctx, sr)
+			return err
+		})
+		done <- a
+	}()
+
+	select {
+	case a := <-done:
+		switch {
+		case a.err != nil:
+			return nil, a.err
+		case a.mr == nil:
+			return nil, fmt.Errorf("%w: no answer", ErrInvalidMove)
+		case !a.mr.Move.Valid():
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMove, a.mr.Move)
+		}
+
+		return a.mr, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("api: brain didn't answer in time: %w", ctx.Err())
+	}
+}
+
+// failureReason sorts the ways CallMove can fail for metrics.
+func failureReason(err error) string {
+	var pe *PanicError
+	switch {
+	case errors.As(err, &pe):
+		return "panic"
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return "timeout"
+	case errors.Is(err, ErrInvalidMove):
+		return "invalid"
+	default:
+		return "error"
+	}
+}
+
+// SafestMove picks a move without any cleverness, for when a brain can't.
+// It avoids walls and bodies that will still be there next turn, then
+// head-to-heads with snakes at least as long as you, then hazards that
+// would finish you off, and prefers whichever move leaves the most room.
+func SafestMove(sr SnakeRequest) Direction {
+	b, me := sr.Board, sr.You
+	if len(me.Body) == 0 {
+		return Up
+	}
+	head := me.Body[0]
+
+	// tails move out of the way, unless the snake just ate and its tail is
+	// stacked
+	clears := b.ClearsAt()
+
+	const (
+		death    = -1000000
+		headLoss = -10000
+	)
+
+	damage := hazardDamage(sr.Game)
+	best, bestScore := Up, death-1
+	for _, dir := range Directions {
+		next := dir.Apply(head)
+		if !b.Inside(next) || clears.At(next) > 1 {
+			if death > bestScore {
+				best, bestScore = dir, death
+			}
+			continue
+		}
+
+		score := reachable(clears, next, 2*len(me.Body))
+
+		for _, sn := range b.Snakes {
+			if sn.ID == me.ID || len(sn.Body) == 0 || len(sn.Body) < len(me.Body) {
+				continue
+			}
+
+			for _, n := range next.Neighbors() {
+				if n.Eq(sn.Body[0]) {
+					score += headLoss
+				}
+			}
+		}
+
+		if b.IsHazard(next) {
+			if me.Health <= damage+1 {
+				score += death / 2
+			} else {
+				score -= 5
+			}
+		}
+
+		if score > bestScore {
+			best, bestScore = dir, score
+		}
+	}
+
+	return best
+}
+
+// hazardDamage is how much health a turn in a hazard costs in game g.
+func hazardDamage(g Game) int {
+	if d := g.Ruleset.Settings.HazardDamagePerTurn; d > 0 {
+		return d
+	}
+
+	return 14
+}
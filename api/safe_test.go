@@ -0,0 +1,42 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestSafestMove(t *testing.T) {
+	me := Snake{ID: "me", Health: 90, Body: []Coord{{X: 2, Y: 2}, {X: 2, Y: 1}, {X: 2, Y: 0}}}
+	sr := SnakeRequest{
+		You: me,
+		Board: Board{
+			Width:  7,
+			Height: 5,
+			Snakes: []Snake{
+				me,
+				// a bigger snake whose head is next to the square above us
+				{ID: "big", Body: []Coord{{X: 2, Y: 4}, {X: 3, Y: 4}, {X: 4, Y: 4}, {X: 5, Y: 4}}},
+				// a wall of body to the left
+				{ID: "wall", Body: []Coord{{X: 1, Y: 3}, {X: 1, Y: 2}, {X: 1, Y: 1}, {X: 1, Y: 0}}},
+			},
+		},
+	}
+
+	if got := SafestMove(sr); got != Right {
+		t.Errorf("wanted right, away from the wall and the head-to-head, got: %s", got)
+	}
+
+	// with a hazard that would kill us to the right, going up next to the
+	// big snake is the lesser evil
+	sr.Board.Hazards = []Coord{{X: 3, Y: 2}}
+	sr.You.Health = 5
+	if got := SafestMove(sr); got != Up {
+		t.Errorf("wanted up, got: %s", got)
+	}
+
+	// tails move out of the way, so following our own tail is fine
+	sr = SnakeRequest{You: Snake{ID: "me", Body: []Coord{{X: 0, Y: 1}, {X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}}}}
+	sr.Board = Board{Width: 2, Height: 2, Snakes: []Snake{sr.You}}
+	if got := SafestMove(sr); got != Right {
+		t.Errorf("wanted to follow the tail right, got: %s", got)
+	}
+}
@@ -1,10 +1,15 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -27,9 +32,19 @@ var (
 		Name: "games_ended",
 		Help: "The number of games ended",
 	}, []string{"brain"})
+
+	moveFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_move_failures",
+		Help: "The number of moves a brain failed to make and the fallback was used for, by why",
+	}, []string{"brain", "reason"})
 )
 
 // AI is an individual snake AI.
+//
+// Moves that take too long are given up on (see CallMove) but not stopped,
+// so a brain can still be working on one turn when the next turn's request
+// comes in. Brains that keep state between turns must not let a late move
+// write over a newer one's; StateStore.PutTurn is one way.
 type AI interface {
 	Ping() (*PingResponse, error)
 	Start(ctx context.Context, sr SnakeRequest) error
@@ -37,15 +52,109 @@ type AI interface {
 	End(ctx context.Context, sr SnakeRequest) error
 }
 
+// Debugger is a brain that can show what it is keeping track of, such as
+// the state it holds for every game it is playing. It is served at
+// /<snake>/debug.
+type Debugger interface {
+	// Debug returns the brain's state in a form that can be written as
+	// JSON, or nil if there is nothing to show.
+	Debug() interface{}
+}
+
+// DebugState asks ai for its state if it is a Debugger.
+func DebugState(ai AI) (interface{}, bool) {
+	d, ok := ai.(Debugger)
+	if !ok {
+		return nil, false
+	}
+
+	state := d.Debug()
+	return state, state != nil
+}
+
+// Namer is a brain that labels its metrics with the name it is served
+// under, so the same brain served twice with different settings can be told
+// apart. SetName is called before the brain is used.
+type Namer interface {
+	SetName(name string)
+}
+
+// Retirer is a brain that holds on to something, such as the state it keeps
+// for every game, that it should let go of once it isn't served any more.
+type Retirer interface {
+	Retire()
+}
+
+// Retire lets go of what ai holds on to if it is a Retirer. Ai must not be
+// used afterwards.
+func Retire(ai AI) {
+	if r, ok := ai.(Retirer); ok {
+		r.Retire()
+	}
+}
+
+// Brains finds brains by the name they are served under.
+type Brains interface {
+	Brain(name string) (AI, bool)
+}
+
+// BrainMap is a fixed set of brains by name.
+type BrainMap map[string]AI
+
+// Brain implements Brains.
+func (bm BrainMap) Brain(name string) (AI, bool) {
+	ai, ok := bm[name]
+	return ai, ok
+}
+
 // Server wraps an AI.
 type Server struct {
 	Brain AI
 	Name  string
+
+	// Recorder, if set, records every request the brain answers.
+	Recorder *Recorder
+
+	// Margin is how long before the engine gives up on us that answers are
+	// due. If zero, DefaultMargin is used.
+	Margin time.Duration
+
+	setup   sync.Once
+	latency latencyTracker
+	moves   Window
 }
 
-func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+// ServeHTTP answers a request from the engine. The brain gets a context
+// with a deadline that leaves time for the answer to get back to the
+// engine: the game's timeout, less the round trip measured from the
+// latencies the engine reports, less Margin, counting from when the request
+// arrived.
+//
+// If the brain fails to pick a move by then, errors or panics, the engine
+// still gets an answer: SafestMove.
+//
+// A GET of /<snake>/debug shows the brain's state, if it is a Debugger.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	arrived := now()
+	s.setup.Do(func() {
+		s.latency.games.Name = s.Name + "_latency"
+	})
 	w.Header().Set("X-Snake-AI", s.Name)
 
+	if r.Method == http.MethodGet && filepath.Base(r.URL.Path) == "debug" {
+		state, ok := DebugState(s.Brain)
+		if !ok {
+			http.Error(w, s.Name+" keeps no state", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		e.Encode(state)
+		return
+	}
+
 	if r.Method != http.MethodPost {
 		result, err := s.Brain.Ping()
 		if err != nil {
@@ -71,10 +180,29 @@ func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := ln.WithF(r.Context(), decoded.F())
 	ctx = opname.With(ctx, s.Name)
 
-	switch filepath.Base(r.URL.Path) {
+	var mr, fallback *MoveResponse
+	kind := filepath.Base(r.URL.Path)
+	ctx, annotations := WithAnnotations(ctx)
+
+	switch kind {
+	case KindStart, KindMove, KindEnd:
+		margin := s.Margin
+		if margin == 0 {
+			margin = DefaultMargin
+		}
+
+		budget := Budget(decoded.Game.MoveTimeout(), s.latency.network(decoded), margin)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, arrived.Add(budget))
+		defer cancel()
+
+		ctx = ln.WithF(ctx, ln.F{"budget": budget})
+	}
+
+	switch kind {
 	case "start":
 		ctx := opname.With(ctx, "start-game")
-		err = s.Brain.Start(ctx, decoded)
+		err = Guard(func() error { return s.Brain.Start(ctx, decoded) })
 		gamesStarted.With(prometheus.Labels{"brain": s.Name}).Inc()
 		if err == nil {
 			ln.Log(ctx, decoded, result)
@@ -82,27 +210,77 @@ func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		result = ln.F{}
 	case "move":
 		ctx := opname.With(ctx, "move")
-		result, err = s.Brain.Move(ctx, decoded)
+		mr, err = CallMove(ctx, s.Brain, decoded)
 		movesMade.With(prometheus.Labels{"brain": s.Name}).Inc()
-		if err == nil {
-			ln.Log(ctx, decoded, result)
+		if err != nil {
+			reason := failureReason(err)
+			moveFailures.With(prometheus.Labels{"brain": s.Name, "reason": reason}).Inc()
+
+			f := ln.F{"failure": reason}
+			var pe *PanicError
+			if errors.As(err, &pe) {
+				f["stack"] = string(pe.Stack)
+			}
+			ln.Error(ctx, err, f)
+
+			// the recording keeps what the brain did, which is nothing
+			mr, fallback = nil, &MoveResponse{Move: SafestMove(decoded)}
+			result = fallback
+		} else {
+			result = mr
 		}
+		ln.Log(ctx, decoded, result)
 	case "end":
 		ctx := opname.With(ctx, "end")
-		err = s.Brain.End(ctx, decoded)
+		err = Guard(func() error { return s.Brain.End(ctx, decoded) })
 		gamesEnded.With(prometheus.Labels{"brain": s.Name}).Inc()
 		ln.Log(ctx, decoded)
 	default:
 		result, err = s.Brain.Ping()
 	}
 
+	// the engine is waiting, so the request is recorded once the answer
+	// has been flushed to it
+	switch kind {
+	case KindStart, KindMove, KindEnd:
+		took, failed := now().Sub(arrived), err
+		defer func() {
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+
+			s.Recorder.save(ctx, s.Name, kind, decoded, mr, fallback, took, annotations(), failed)
+		}()
+	}
+
+	if kind == KindMove {
+		// the failure was dealt with above, the fallback move is the answer
+		err = nil
+	}
+
+	defer func() {
+		switch kind {
+		case KindMove:
+			took := now().Sub(arrived)
+			s.latency.answered(decoded, took)
+			s.moves.Add(took)
+		case KindEnd:
+			s.latency.end(decoded)
+		}
+	}()
+
 	if err != nil {
 		ln.Error(ctx, err)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	// with a length the engine knows the answer is complete once it is
+	// flushed, without waiting for the handler to return
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(result)
+	buf.WriteString("\n")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
-	w.Write([]byte("\n"))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Write(buf.Bytes())
 }
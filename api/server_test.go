@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type brokenBrain struct {
+	testBrain
+	move func(ctx context.Context) (*MoveResponse, error)
+}
+
+func (b brokenBrain) Move(ctx context.Context, sr SnakeRequest) (*MoveResponse, error) {
+	return b.move(ctx)
+}
+
+func TestServerFallback(t *testing.T) {
+	var sr SnakeRequest
+	if err := json.Unmarshal([]byte(v1Request), &sr); err != nil {
+		t.Fatal(err)
+	}
+
+	// we are in the bottom left corner going left, so up is the only way
+	// out
+	sr.Game.Timeout = 100
+
+	cases := map[string]func(ctx context.Context) (*MoveResponse, error){
+		"error": func(context.Context) (*MoveResponse, error) { return nil, errors.New("oops") },
+		"panic": func(context.Context) (*MoveResponse, error) { panic("oh no") },
+		"nil":   func(context.Context) (*MoveResponse, error) { return nil, nil },
+		"timeout": func(ctx context.Context) (*MoveResponse, error) {
+			time.Sleep(time.Second)
+			return &MoveResponse{Move: Down}, nil
+		},
+	}
+
+	for name, move := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &Server{Brain: brokenBrain{move: move}, Name: "test"}
+
+			start := time.Now()
+			w := post(t, s, "/test/move", sr)
+			if w.Code != http.StatusOK {
+				t.Fatalf("failures should still get an answer, got: %d %s", w.Code, w.Body)
+			}
+
+			if took := time.Since(start); took > 100*time.Millisecond {
+				t.Errorf("the answer should come before the timeout, took: %s", took)
+			}
+
+			var mr MoveResponse
+			if err := json.NewDecoder(w.Body).Decode(&mr); err != nil {
+				t.Fatal(err)
+			}
+
+			if mr.Move != Up {
+				t.Errorf("wanted the safest move up, got: %s", mr.Move)
+			}
+		})
+	}
+}
+
+func TestRecordedFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bsnk-recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var sr SnakeRequest
+	if err := json.Unmarshal([]byte(v1Request), &sr); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &Recorder{Dir: dir}
+	defer rec.Close()
+
+	broken := brokenBrain{move: func(context.Context) (*MoveResponse, error) { return nil, errors.New("oops") }}
+	s := &Server{Brain: broken, Name: "test", Recorder: rec}
+	for _, kind := range []string{KindStart, KindMove, KindEnd} {
+		if w := post(t, s, "/test/"+kind, sr); w.Code != http.StatusOK {
+			t.Fatalf("%s failed: %d %s", kind, w.Code, w.Body)
+		}
+	}
+
+	records, err := OpenRecording(filepath.Join(dir, RecordingPath("test", sr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("wanted 3 records, got: %d", len(records))
+	}
+
+	if r := records[1]; r.Response != nil || r.Fallback == nil || r.Fallback.Move != Up || r.Error != "oops" {
+		t.Errorf("the brain's failure should be recorded with the fallback kept apart: %#v", r)
+	}
+
+	diffs, err := Replay(context.Background(), testBrain{move: Left}, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diffs) != 1 || diffs[0].Recorded() != NoDirection || diffs[0].Move != Left {
+		t.Errorf("replaying a failed move should show the brain failed, got: %#v", diffs)
+	}
+}
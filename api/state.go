@@ -0,0 +1,308 @@
+package api
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	stateGames = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "state_store_games",
+		Help: "The number of games with state kept in a state store",
+	}, []string{"store"})
+
+	stateEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "state_store_evictions",
+		Help: "The number of games evicted from a state store before they ended, by why",
+	}, []string{"store", "reason"})
+)
+
+// GameKey is one snake in one game. The same brain can play a game as more
+// than one snake, so both are needed to tell its games apart.
+type GameKey struct {
+	GameID  string
+	SnakeID string
+}
+
+// KeyOf is the key for the game and snake a request is for.
+func KeyOf(sr SnakeRequest) GameKey {
+	return GameKey{GameID: sr.Game.ID, SnakeID: sr.You.ID}
+}
+
+// stateShards is how many locks a StateStore spreads its games over.
+const stateShards = 16
+
+// StateStore keeps a value for every game a brain is playing, for brains
+// that need to remember things between turns. Games that haven't been
+// touched in a while are evicted, for when their end request never comes.
+//
+// The zero value is ready to use. A StateStore is safe for concurrent use;
+// games are spread over several locks so busy games don't wait on each
+// other. A StateStore must not be copied after first use.
+type StateStore struct {
+	// Name labels the store's metrics.
+	Name string
+
+	// TTL is how long a game can go untouched before it is evicted. If zero,
+	// ten minutes is used.
+	TTL time.Duration
+
+	// MaxGames is the most games to keep. When full, the game touched least
+	// recently is evicted. Zero means no limit.
+	MaxGames int
+
+	shards    [stateShards]stateShard
+	games     int64
+	lastSweep int64
+	closed    int32
+}
+
+type stateShard struct {
+	lock  sync.Mutex
+	games map[GameKey]*stateEntry
+}
+
+type stateEntry struct {
+	value    interface{}
+	turn     int
+	lastUsed time.Time
+}
+
+func (s *StateStore) shard(key GameKey) *stateShard {
+	h := fnv.New32a()
+	h.Write([]byte(key.GameID))
+	h.Write([]byte{0})
+	h.Write([]byte(key.SnakeID))
+
+	return &s.shards[h.Sum32()%stateShards]
+}
+
+func (s *StateStore) ttl() time.Duration {
+	if s.TTL == 0 {
+		return 10 * time.Minute
+	}
+
+	return s.TTL
+}
+
+// Get returns the value kept for a game.
+func (s *StateStore) Get(key GameKey) (interface{}, bool) {
+	s.sweep()
+
+	sh := s.shard(key)
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+
+	e, ok := sh.games[key]
+	if !ok {
+		return nil, false
+	}
+	e.lastUsed = time.Now()
+
+	return e.value, true
+}
+
+// Put keeps value for a game, replacing what was there.
+func (s *StateStore) Put(key GameKey, value interface{}) {
+	s.Update(key, func(interface{}, bool) interface{} {
+		return value
+	})
+}
+
+// PutTurn keeps value for a game as of turn, unless something newer was
+// kept with PutTurn already. It reports whether value was kept.
+//
+// A brain CallMove gave up on carries on in the background, and can finish
+// after the next turn's move has started or even finished. Brains that keep
+// what they worked out each turn should use PutTurn, so a late move doesn't
+// write over a newer one.
+func (s *StateStore) PutTurn(key GameKey, turn int, value interface{}) bool {
+	kept := false
+	s.update(key, func(e *stateEntry, ok bool) {
+		if !ok || turn >= e.turn {
+			e.value, e.turn, kept = value, turn, true
+		}
+	})
+
+	return kept
+}
+
+// Update replaces the value kept for a game with what fn makes of it, with
+// the game locked so nothing else changes it in between. Ok is false when
+// there was nothing kept for the game yet.
+func (s *StateStore) Update(key GameKey, fn func(value interface{}, ok bool) interface{}) interface{} {
+	var value interface{}
+	s.update(key, func(e *stateEntry, ok bool) {
+		e.value = fn(e.value, ok)
+		value = e.value
+	})
+
+	return value
+}
+
+// update calls fn with a game's entry locked, adding the entry if there
+// wasn't one.
+func (s *StateStore) update(key GameKey, fn func(e *stateEntry, ok bool)) {
+	s.sweep()
+
+	sh := s.shard(key)
+	sh.lock.Lock()
+
+	if sh.games == nil {
+		sh.games = map[GameKey]*stateEntry{}
+	}
+
+	e, ok := sh.games[key]
+	if !ok {
+		e = &stateEntry{}
+	}
+
+	fn(e, ok)
+	e.lastUsed = time.Now()
+
+	// a closed store keeps nothing, but fn still gets to run
+	if !ok && atomic.LoadInt32(&s.closed) != 0 {
+		sh.lock.Unlock()
+		return
+	}
+
+	if !ok {
+		sh.games[key] = e
+		atomic.AddInt64(&s.games, 1)
+		stateGames.With(prometheus.Labels{"store": s.Name}).Inc()
+	}
+	sh.lock.Unlock()
+
+	if !ok && s.MaxGames > 0 && atomic.LoadInt64(&s.games) > int64(s.MaxGames) {
+		s.evictOldest(key)
+	}
+}
+
+// Delete forgets a game, such as when it ends.
+func (s *StateStore) Delete(key GameKey) {
+	sh := s.shard(key)
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+
+	s.remove(sh, key)
+}
+
+// Close forgets every game, and the store keeps none from then on. It is for
+// when the brain the store belongs to is retired, so its games stop being
+// counted in the store's metrics.
+func (s *StateStore) Close() {
+	atomic.StoreInt32(&s.closed, 1)
+
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.lock.Lock()
+		for key := range sh.games {
+			s.remove(sh, key)
+		}
+		sh.lock.Unlock()
+	}
+}
+
+// remove deletes a game from a locked shard.
+func (s *StateStore) remove(sh *stateShard, key GameKey) bool {
+	if _, ok := sh.games[key]; !ok {
+		return false
+	}
+
+	delete(sh.games, key)
+	atomic.AddInt64(&s.games, -1)
+	stateGames.With(prometheus.Labels{"store": s.Name}).Dec()
+
+	return true
+}
+
+// Len is how many games are kept.
+func (s *StateStore) Len() int {
+	return int(atomic.LoadInt64(&s.games))
+}
+
+// Each calls fn with every game kept, in no particular order, until fn
+// returns false. Fn must not use the store.
+func (s *StateStore) Each(fn func(key GameKey, value interface{}) bool) {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.lock.Lock()
+		for key, e := range sh.games {
+			if !fn(key, e.value) {
+				sh.lock.Unlock()
+				return
+			}
+		}
+		sh.lock.Unlock()
+	}
+}
+
+// Sweep evicts every game that has been idle for longer than the TTL. It
+// happens on its own as the store is used, but can be called to make sure.
+func (s *StateStore) Sweep() {
+	atomic.StoreInt64(&s.lastSweep, time.Now().UnixNano())
+
+	cutoff := time.Now().Add(-s.ttl())
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.lock.Lock()
+		for key, e := range sh.games {
+			if e.lastUsed.Before(cutoff) && s.remove(sh, key) {
+				stateEvictions.With(prometheus.Labels{"store": s.Name, "reason": "idle"}).Inc()
+			}
+		}
+		sh.lock.Unlock()
+	}
+}
+
+// sweep sweeps every so often.
+func (s *StateStore) sweep() {
+	last := atomic.LoadInt64(&s.lastSweep)
+	now := time.Now().UnixNano()
+	if time.Duration(now-last) < s.ttl()/4 {
+		return
+	}
+
+	// only one caller sweeps
+	if atomic.CompareAndSwapInt64(&s.lastSweep, last, now) {
+		s.Sweep()
+	}
+}
+
+// evictOldest evicts the game touched least recently, other than keep. The
+// store is full so rarely that going through every game is fine.
+func (s *StateStore) evictOldest(keep GameKey) {
+	var (
+		oldest   GameKey
+		oldestAt time.Time
+		found    bool
+	)
+
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.lock.Lock()
+		for key, e := range sh.games {
+			if key != keep && (!found || e.lastUsed.Before(oldestAt)) {
+				oldest, oldestAt, found = key, e.lastUsed, true
+			}
+		}
+		sh.lock.Unlock()
+	}
+
+	if !found {
+		return
+	}
+
+	sh := s.shard(oldest)
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+
+	if s.remove(sh, oldest) {
+		stateEvictions.With(prometheus.Labels{"store": s.Name, "reason": "full"}).Inc()
+	}
+}
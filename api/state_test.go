@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStateStore(t *testing.T) {
+	s := &StateStore{Name: "test", MaxGames: 3}
+	key := func(i int) GameKey { return GameKey{GameID: fmt.Sprintf("game-%d", i), SnakeID: "me"} }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Update(key(0), func(v interface{}, ok bool) interface{} {
+					n, _ := v.(int)
+					return n + 1
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, ok := s.Get(key(0)); !ok || v.(int) != 400 {
+		t.Errorf("updates should not be lost, got: %v", v)
+	}
+
+	for i := 1; i <= 3; i++ {
+		time.Sleep(time.Millisecond)
+		s.Put(key(i), i)
+	}
+
+	if s.Len() != 3 {
+		t.Errorf("wanted 3 games, got: %d", s.Len())
+	}
+
+	if _, ok := s.Get(key(0)); ok {
+		t.Error("the game touched least recently should have been evicted")
+	}
+
+	s.Delete(key(3))
+	seen := map[GameKey]interface{}{}
+	s.Each(func(k GameKey, v interface{}) bool {
+		seen[k] = v
+		return true
+	})
+
+	if want := map[GameKey]interface{}{key(1): 1, key(2): 2}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("wanted %v, got: %v", want, seen)
+	}
+
+	// a move that finishes late doesn't write over the turn after it
+	if !s.PutTurn(key(1), 5, "five") || s.PutTurn(key(1), 4, "four") {
+		t.Error("only the newer turn should be kept")
+	}
+	if v, _ := s.Get(key(1)); v != "five" {
+		t.Errorf("wanted the newer turn kept, got: %v", v)
+	}
+
+	s.TTL = time.Millisecond
+	time.Sleep(2 * time.Millisecond)
+	s.Sweep()
+	if s.Len() != 0 {
+		t.Errorf("idle games should have been evicted, %d left", s.Len())
+	}
+
+	if (KeyOf(SnakeRequest{Game: Game{ID: "g"}, You: Snake{ID: "s"}})) != (GameKey{GameID: "g", SnakeID: "s"}) {
+		t.Error("wrong key")
+	}
+
+	// a closed store forgets its games and keeps no new ones
+	s.Put(key(1), 1)
+	s.Close()
+	s.Put(key(2), 2)
+	if _, ok := s.Get(key(1)); ok || s.Len() != 0 {
+		t.Errorf("a closed store should keep nothing, has %d games", s.Len())
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Window keeps the most recent durations seen, to get percentiles of. The
+// zero value keeps the last 1000. A Window is safe for concurrent use.
+type Window struct {
+	// Size is how many durations to keep. If zero, 1000 are kept.
+	Size int
+
+	lock    sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+// Add notes a duration, pushing out the oldest one if the window is full.
+func (w *Window) Add(d time.Duration) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	size := w.Size
+	if size == 0 {
+		size = 1000
+	}
+
+	if len(w.samples) < size {
+		w.samples = append(w.samples, d)
+		return
+	}
+
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % size
+}
+
+// Len is how many durations are in the window.
+func (w *Window) Len() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return len(w.samples)
+}
+
+// Percentiles finds the given percentiles, from 0 to 100, of the durations
+// in the window. They are all zero if the window is empty.
+func (w *Window) Percentiles(ps ...float64) []time.Duration {
+	w.lock.Lock()
+	sorted := append([]time.Duration(nil), w.samples...)
+	w.lock.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	result := make([]time.Duration, len(ps))
+	if len(sorted) == 0 {
+		return result
+	}
+
+	for i, p := range ps {
+		// nearest rank
+		rank := int(p/100*float64(len(sorted)) + 0.5)
+		switch {
+		case rank < 1:
+			rank = 1
+		case rank > len(sorted):
+			rank = len(sorted)
+		}
+
+		result[i] = sorted[rank-1]
+	}
+
+	return result
+}
+
+// ServerStats is how a Server has been doing lately.
+type ServerStats struct {
+	// LiveGames is how many games the brain is playing.
+	LiveGames int `json:"live_games"`
+
+	// Moves is how many of the most recent moves the latencies are of.
+	Moves int `json:"moves"`
+
+	// P50, P90 and P99 are percentiles of how long the most recent moves
+	// took to answer, from when each request arrived.
+	P50 Duration `json:"p50"`
+	P90 Duration `json:"p90"`
+	P99 Duration `json:"p99"`
+}
+
+// Duration is a time.Duration that is written as JSON like "12.5ms".
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Stats reports how the server has been doing lately.
+func (s *Server) Stats() ServerStats {
+	ps := s.moves.Percentiles(50, 90, 99)
+
+	return ServerStats{
+		LiveGames: s.latency.games.Len(),
+		Moves:     s.moves.Len(),
+		P50:       Duration(ps[0]),
+		P90:       Duration(ps[1]),
+		P99:       Duration(ps[2]),
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWindow(t *testing.T) {
+	w := Window{Size: 100}
+	if got := w.Percentiles(50); got[0] != 0 {
+		t.Errorf("an empty window should have no percentiles, got: %s", got[0])
+	}
+
+	// the first 100 are pushed out by the last 100
+	for i := 1; i <= 200; i++ {
+		w.Add(time.Duration(i) * time.Millisecond)
+	}
+
+	if w.Len() != 100 {
+		t.Fatalf("wanted 100 durations kept, got: %d", w.Len())
+	}
+
+	got := w.Percentiles(0, 50, 99, 100)
+	want := []time.Duration{101 * time.Millisecond, 150 * time.Millisecond, 199 * time.Millisecond, 200 * time.Millisecond}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted percentiles %v, got: %v", want, got)
+	}
+}
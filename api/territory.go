@@ -0,0 +1,195 @@
+package api
+
+// Contested marks a square in Territory.Owner that two snakes of the same
+// length get to at the same time, so neither can safely have it.
+const Contested = -1
+
+// Unreached marks a square in Territory.Owner that no snake can get to.
+const Unreached = -2
+
+// Territory is which snake gets to each square of a board first, counting
+// on bodies moving out of the way as in ClearsAt. When snakes get to a
+// square on the same turn the longest one has it, as it would win the
+// head-to-head.
+//
+// A Territory can be reused for many boards with Compute, which only
+// allocates when the board is bigger or has more snakes than before. That
+// makes it cheap enough to run on every position a search looks at.
+type Territory struct {
+	Width, Height int
+
+	// Owner is, for every square in Grid.Index order, the index in
+	// Board.Snakes of the snake that gets there first, Contested or
+	// Unreached.
+	Owner []int
+
+	// Turns is, for every square in Grid.Index order, how many turns it
+	// takes the owner to get there.
+	Turns []int
+
+	// Squares and Food are how many squares and how much food each snake
+	// has, by index in Board.Snakes.
+	Squares []int
+	Food    []int
+
+	clears  []int
+	lengths []int
+	queue   []int
+	heads   []int
+	sizes   []int
+}
+
+// Territory works out which snake gets to each square of the board first.
+func (b Board) Territory() *Territory {
+	t := &Territory{}
+	t.Compute(b)
+
+	return t
+}
+
+// Compute works out which snake gets to each square of b first, replacing
+// what t held.
+func (t *Territory) Compute(b Board) {
+	t.heads, t.sizes = t.heads[:0], t.sizes[:0]
+	for _, sn := range b.Snakes {
+		head := -1
+		if len(sn.Body) > 0 && b.Inside(sn.Body[0]) {
+			head = sn.Body[0].Y*b.Width + sn.Body[0].X
+		}
+
+		t.heads = append(t.heads, head)
+		t.sizes = append(t.sizes, len(sn.Body))
+	}
+
+	t.Width, t.Height = b.Width, b.Height
+	t.ComputeCells(Cells{
+		Neighbors: NeighborTable(b.Width, b.Height),
+		Heads:     t.heads,
+		Lengths:   t.sizes,
+		Clears: func(clears []int) {
+			b.clearsInto(Grid{Width: b.Width, Height: b.Height, Cells: clears})
+		},
+	})
+
+	for _, f := range b.Food {
+		if b.Inside(f) {
+			if owner := t.Owner[f.Y*b.Width+f.X]; owner >= 0 {
+				t.Food[owner]++
+			}
+		}
+	}
+}
+
+// Cells is a board as squares numbered like Grid.Index, so Territory can be
+// worked out for boards kept in other forms than Board.
+type Cells struct {
+	// Neighbors is, for every square, the square one step away in each
+	// direction, or -1 off the board, as in NeighborTable.
+	Neighbors [][4]int
+
+	// Heads and Lengths are, for every snake, the square its head is on, or
+	// -1 if it isn't on the board, and how long it is.
+	Heads, Lengths []int
+
+	// Clears fills a zeroed square for square slice with how many turns
+	// until each square is free to move into, as in Board.ClearsAt.
+	Clears func(clears []int)
+}
+
+// ComputeCells works out which snake gets to each square of c first,
+// replacing what t held other than Width and Height. Food is zeroed for the
+// caller to count, since it is kept differently from board to board too.
+func (t *Territory) ComputeCells(c Cells) {
+	size := len(c.Neighbors)
+	t.Owner = resize(t.Owner, size)
+	t.Turns = resize(t.Turns, size)
+	t.clears = resize(t.clears, size)
+	t.lengths = resize(t.lengths, size)
+	t.Squares = resize(t.Squares, len(c.Heads))
+	t.Food = resize(t.Food, len(c.Heads))
+	t.queue = t.queue[:0]
+
+	for i := range t.Owner {
+		t.Owner[i] = Unreached
+		t.Turns[i] = 0
+		t.clears[i] = 0
+		t.lengths[i] = 0
+	}
+	for i := range t.Squares {
+		t.Squares[i] = 0
+		t.Food[i] = 0
+	}
+
+	c.Clears(t.clears)
+
+	for i, head := range c.Heads {
+		// heads are where their snakes already are, even if two are on the
+		// same square after a collision
+		if head >= 0 && t.Owner[head] == Unreached {
+			t.Owner[head], t.lengths[head] = i, c.Lengths[i]
+			t.queue = append(t.queue, head)
+		}
+	}
+
+	// breadth first from every head at once, a turn at a time
+	for start := 0; start < len(t.queue); {
+		end := len(t.queue)
+		for _, sq := range t.queue[start:end] {
+			owner := t.Owner[sq]
+			if owner < 0 {
+				continue
+			}
+
+			turn, length := t.Turns[sq]+1, c.Lengths[owner]
+			for _, n := range c.Neighbors[sq] {
+				if n >= 0 {
+					t.claim(n, owner, length, turn)
+				}
+			}
+		}
+		start = end
+	}
+
+	for _, owner := range t.Owner {
+		if owner >= 0 {
+			t.Squares[owner]++
+		}
+	}
+}
+
+// claim has snake owner, which is length long, get to square c on turn.
+func (t *Territory) claim(c, owner, length, turn int) {
+	switch {
+	case t.clears[c] > turn:
+		// still in the way
+		return
+	case t.Owner[c] == Unreached:
+		t.Owner[c], t.Turns[c], t.lengths[c] = owner, turn, length
+		t.queue = append(t.queue, c)
+	case t.Turns[c] != turn || t.Owner[c] == owner:
+		// someone got there first
+	case length > t.lengths[c]:
+		t.Owner[c], t.lengths[c] = owner, length
+	case length == t.lengths[c]:
+		t.Owner[c] = Contested
+	}
+}
+
+// OwnerAt is the index in Board.Snakes of the snake that gets to c first,
+// Contested or Unreached.
+func (t *Territory) OwnerAt(c Coord) int {
+	if c.X < 0 || c.Y < 0 || c.X >= t.Width || c.Y >= t.Height {
+		return Unreached
+	}
+
+	return t.Owner[c.Y*t.Width+c.X]
+}
+
+// resize makes s n long, reusing its memory if there is enough.
+func resize(s []int, n int) []int {
+	if cap(s) < n {
+		return make([]int, n)
+	}
+
+	return s[:n]
+}
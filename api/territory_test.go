@@ -0,0 +1,52 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerritory(t *testing.T) {
+	// a 7x1 corridor, with a long snake on the left and a short one on the
+	// right that both get to (3,0) on the first turn
+	long := Snake{ID: "long", Body: []Coord{{X: 2, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 0}}}
+	short := Snake{ID: "short", Body: []Coord{{X: 4, Y: 0}, {X: 5, Y: 0}}}
+	b := Board{Width: 7, Height: 1, Snakes: []Snake{long, short}, Food: []Coord{{X: 3, Y: 0}, {X: 6, Y: 0}}}
+
+	tr := b.Territory()
+	want := []int{Unreached, Unreached, 0, 0, 1, 1, 1}
+	if !reflect.DeepEqual(tr.Owner, want) {
+		t.Errorf("wanted owners %v, got: %v", want, tr.Owner)
+	}
+
+	if !reflect.DeepEqual(tr.Squares, []int{2, 3}) || !reflect.DeepEqual(tr.Food, []int{1, 1}) {
+		t.Errorf("wanted long to have 2 squares and short 3, and a food each, got squares %v, food %v", tr.Squares, tr.Food)
+	}
+
+	// at the same length neither can have it
+	b.Snakes[1].Body = append(b.Snakes[1].Body, Coord{X: 6, Y: 0})
+	tr.Compute(b)
+	if got := tr.OwnerAt(Coord{X: 3, Y: 0}); got != Contested {
+		t.Errorf("(3,0) should be contested, got: %d", got)
+	}
+
+	if got := tr.OwnerAt(Coord{X: 9, Y: 0}); got != Unreached {
+		t.Errorf("squares off the board should be unreached, got: %d", got)
+	}
+
+	// tails that move out of the way become territory in time
+	b = Board{Width: 3, Height: 3, Snakes: []Snake{{ID: "me", Body: []Coord{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}}}}}
+	tr.Compute(b)
+	if tr.OwnerAt(Coord{X: 2, Y: 0}) != 0 || tr.Squares[0] != 9 {
+		t.Errorf("wanted the whole board, got: %v", tr.Owner)
+	}
+}
+
+func BenchmarkTerritory(b *testing.B) {
+	board := benchmarkBoard()
+	var tr Territory
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		tr.Compute(board)
+	}
+}
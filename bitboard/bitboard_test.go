@@ -0,0 +1,265 @@
+package bitboard
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/rules"
+)
+
+// same checks that a state and a board have the same snakes and food.
+func same(t *testing.T, turn int, s *State, want api.Board) {
+	t.Helper()
+
+	got := s.Board()
+	if len(got.Snakes) != len(want.Snakes) {
+		t.Fatalf("turn %d: wanted %d snakes, got %d", turn, len(want.Snakes), len(got.Snakes))
+	}
+
+	for i, sn := range want.Snakes {
+		g := got.Snakes[i]
+		if g.ID != sn.ID || g.Health != sn.Health || !reflect.DeepEqual(g.Body, sn.Body) {
+			t.Fatalf("turn %d: wanted %s with %d health at %v, got %s with %d health at %v", turn, sn.ID, sn.Health, sn.Body, g.ID, g.Health, g.Body)
+		}
+	}
+
+	food := append([]api.Coord(nil), want.Food...)
+	sort.Slice(food, func(i, j int) bool { return food[i].Y*want.Width+food[i].X < food[j].Y*want.Width+food[j].X })
+	if len(food) == 0 {
+		food = []api.Coord{}
+	}
+
+	if !reflect.DeepEqual(got.Food, food) {
+		t.Fatalf("turn %d: wanted food at %v, got %v", turn, food, got.Food)
+	}
+}
+
+func TestMakeUnmake(t *testing.T) {
+	settings := api.RulesetSettings{HazardDamagePerTurn: 14}
+
+	for seed := int64(1); seed <= 50; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		b, err := rules.CreateInitialBoard(7, 7, []string{"a", "b", "c", "d"}, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Hazards = []api.Coord{{X: 0, Y: 3}, {X: 1, Y: 3}, {X: 2, Y: 3}}
+
+		sr := api.SnakeRequest{
+			Game:  api.Game{Ruleset: api.Ruleset{Name: "standard", Settings: settings}},
+			Board: b,
+			You:   b.Snakes[0],
+		}
+		s := FromRequest(sr)
+		start := rules.CopyBoard(b)
+		same(t, 0, s, start)
+
+		r := rules.NewStandard(settings, seed)
+		var history []api.Board
+		for turn := 1; len(b.Snakes) > 0 && turn < 100; turn++ {
+			var moves []rules.SnakeMove
+			dirs := make([]api.Direction, len(s.Snakes))
+			for i, sn := range s.Snakes {
+				// mostly sensible moves so games last, with the odd mistake
+				head := sn.Head()
+				for _, j := range rng.Perm(4) {
+					dirs[i] = api.Directions[j]
+					if sn.Alive && (!s.IsDeadly(s.Neighbors[head][j]) || rng.Intn(10) == 0) {
+						break
+					}
+				}
+				moves = append(moves, rules.SnakeMove{ID: sn.ID, Move: dirs[i]})
+			}
+
+			history = append(history, b)
+			b, _, err = r.Next(turn, b, moves)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s.Make(dirs)
+			same(t, turn, s, b)
+			if s.Hash != s.ComputeHash() {
+				t.Fatalf("seed %d turn %d: the hash drifted from the position", seed, turn)
+			}
+		}
+
+		for i := len(history) - 1; i >= 0; i-- {
+			s.Unmake()
+			same(t, i, s, history[i])
+			if s.Hash != s.ComputeHash() {
+				t.Fatalf("seed %d turn %d: the hash drifted from the position after unmaking", seed, i)
+			}
+		}
+
+		if s.Depth() != 0 || s.Turn != 0 {
+			t.Errorf("seed %d: wanted every move unmade, got depth %d on turn %d", seed, s.Depth(), s.Turn)
+		}
+
+		if !reflect.DeepEqual(s.Request(0).You.Body, start.Snakes[0].Body) {
+			t.Errorf("seed %d: wanted you back where you started", seed)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	sr := benchmarkRequest()
+	s := FromRequest(sr)
+	start := s.Hash
+
+	s.Make([]api.Direction{api.Up, api.Up, api.Up, api.Up})
+	up := s.Hash
+	s.Unmake()
+	if s.Hash != start {
+		t.Errorf("wanted unmaking to put the hash back")
+	}
+
+	s.Make([]api.Direction{api.Left, api.Left, api.Left, api.Left})
+	if s.Hash == up || s.Hash == start {
+		t.Errorf("wanted different positions to hash differently")
+	}
+
+	if other := FromRequest(sr); other.Hash != start {
+		t.Errorf("wanted the same position to hash the same every time")
+	}
+}
+
+func TestTable(t *testing.T) {
+	for _, policy := range []Policy{TwoTier, DepthPreferred, AlwaysReplace} {
+		tt := NewTable(100, policy)
+		if len(tt.slots) != 64 {
+			t.Fatalf("wanted the size rounded down to 64, got %d", len(tt.slots))
+		}
+
+		want := Entry{Score: -12.5, Depth: 3, Bound: Lower, Move: api.Right}
+		tt.Store(42, want)
+		got, ok := tt.Probe(42)
+		if !ok || got.Score != want.Score || got.Depth != want.Depth || got.Bound != want.Bound || got.Move != want.Move {
+			t.Errorf("policy %d: wanted %+v back, got %+v %v", policy, want, got, ok)
+		}
+
+		if _, ok := tt.Probe(43); ok {
+			t.Errorf("policy %d: wanted nothing for a position never stored", policy)
+		}
+
+		// 42, 42+64 and 42+128 all want the same two slots
+		tt.Store(42+64, Entry{Depth: 1})
+		tt.Store(42+128, Entry{Depth: 2})
+		_, deep := tt.Probe(42)
+		_, recent := tt.Probe(42 + 128)
+
+		switch policy {
+		case TwoTier:
+			if !deep || !recent {
+				t.Errorf("two tier: wanted the deepest and the newest kept, got %v %v", deep, recent)
+			}
+		case DepthPreferred:
+			if !deep {
+				t.Errorf("depth preferred: wanted the deepest kept")
+			}
+		case AlwaysReplace:
+			if !recent {
+				t.Errorf("always replace: wanted the newest kept")
+			}
+		}
+
+		// after a new search starts, old entries give way to shallow new ones
+		tt.Age()
+		tt.Store(42+192, Entry{Depth: 0, Move: api.Up})
+		if e, ok := tt.Probe(42 + 192); !ok || e.Move != api.Up {
+			t.Errorf("policy %d: wanted a stale entry replaced, got %+v %v", policy, e, ok)
+		}
+
+		tt.Clear()
+		if _, ok := tt.Probe(42 + 192); ok {
+			t.Errorf("policy %d: wanted nothing after clearing", policy)
+		}
+	}
+}
+
+func TestTableConcurrent(t *testing.T) {
+	tt := NewTable(1<<10, TwoTier)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 10000; i++ {
+				hash := uint64(i%300) * 0x9e3779b97f4a7c15
+				if i%2 == g%2 {
+					tt.Store(hash, Entry{Score: float32(i % 300), Depth: g})
+					continue
+				}
+
+				// whatever comes back must be what was stored for that hash
+				if e, ok := tt.Probe(hash); ok && e.Score != float32(i%300) {
+					t.Errorf("got a torn entry for %d: %+v", i%300, e)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// benchmarkRequest is a busy 11x11 board with four snakes.
+func benchmarkRequest() api.SnakeRequest {
+	b, _ := rules.CreateInitialBoard(11, 11, []string{"a", "b", "c", "d"}, rand.New(rand.NewSource(1)))
+	for i := range b.Snakes {
+		for j := 0; j < 6; j++ {
+			b.Snakes[i].Body = append(b.Snakes[i].Body, b.Snakes[i].Body[0])
+		}
+	}
+
+	return api.SnakeRequest{Board: b, You: b.Snakes[0]}
+}
+
+func BenchmarkIsDeadly(b *testing.B) {
+	sr := benchmarkRequest()
+
+	b.Run("board", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for x := 0; x < sr.Board.Width; x++ {
+				for y := 0; y < sr.Board.Height; y++ {
+					sr.Board.IsDeadly(api.Coord{X: x, Y: y})
+				}
+			}
+		}
+	})
+
+	b.Run("bitboard", func(b *testing.B) {
+		s := FromRequest(sr)
+		for i := 0; i < b.N; i++ {
+			for c := 0; c < s.Cells; c++ {
+				s.IsDeadly(c)
+			}
+		}
+	})
+}
+
+func BenchmarkMove(b *testing.B) {
+	sr := benchmarkRequest()
+	moves := []rules.SnakeMove{{ID: "a", Move: api.Up}, {ID: "b", Move: api.Up}, {ID: "c", Move: api.Up}, {ID: "d", Move: api.Up}}
+
+	b.Run("rules", func(b *testing.B) {
+		r := rules.NewStandard(api.RulesetSettings{}, 1)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r.Next(1, sr.Board, moves)
+		}
+	})
+
+	b.Run("bitboard", func(b *testing.B) {
+		s := FromRequest(sr)
+		dirs := []api.Direction{api.Up, api.Up, api.Up, api.Up}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.Make(dirs)
+			s.Unmake()
+		}
+	})
+}
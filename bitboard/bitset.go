@@ -0,0 +1,53 @@
+package bitboard
+
+import "math/bits"
+
+// Bitset is a set of squares, one bit each.
+type Bitset []uint64
+
+// NewBitset makes an empty set that can hold squares 0 through n-1.
+func NewBitset(n int) Bitset {
+	return make(Bitset, (n+63)/64)
+}
+
+// Has reports whether i is in the set.
+func (b Bitset) Has(i int) bool {
+	return b[i>>6]&(1<<(uint(i)&63)) != 0
+}
+
+// Set adds i to the set.
+func (b Bitset) Set(i int) {
+	b[i>>6] |= 1 << (uint(i) & 63)
+}
+
+// Clear takes i out of the set.
+func (b Bitset) Clear(i int) {
+	b[i>>6] &^= 1 << (uint(i) & 63)
+}
+
+// Count is how many squares are in the set.
+func (b Bitset) Count() int {
+	n := 0
+	for _, w := range b {
+		n += bits.OnesCount64(w)
+	}
+
+	return n
+}
+
+// Reset empties the set.
+func (b Bitset) Reset() {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// Each calls fn with every square in the set, in order.
+func (b Bitset) Each(fn func(i int)) {
+	for wi, w := range b {
+		for w != 0 {
+			fn(wi*64 + bits.TrailingZeros64(w))
+			w &= w - 1
+		}
+	}
+}
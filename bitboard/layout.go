@@ -0,0 +1,58 @@
+package bitboard
+
+import (
+	"sync"
+
+	"github.com/Xe/bsnk/api"
+)
+
+// Layout is everything about a board that only depends on its size, worked
+// out once and shared by every State of that size.
+type Layout struct {
+	Width, Height int
+
+	// Cells is Width*Height. Squares are numbered like api.Grid.Index.
+	Cells int
+
+	// Neighbors is, for every square, the square one step away in each
+	// direction, by Direction-1, or -1 off the board.
+	Neighbors [][4]int
+
+	keys *zobristKeys
+}
+
+var layouts sync.Map // [2]int -> *Layout
+
+// LayoutFor is the layout of a width by height board.
+func LayoutFor(width, height int) *Layout {
+	key := [2]int{width, height}
+	if l, ok := layouts.Load(key); ok {
+		return l.(*Layout)
+	}
+
+	l := &Layout{
+		Width:     width,
+		Height:    height,
+		Cells:     width * height,
+		Neighbors: api.NeighborTable(width, height),
+		keys:      newZobristKeys(width * height),
+	}
+
+	actual, _ := layouts.LoadOrStore(key, l)
+	return actual.(*Layout)
+}
+
+// Inside reports whether c is on the board.
+func (l *Layout) Inside(c api.Coord) bool {
+	return c.X >= 0 && c.Y >= 0 && c.X < l.Width && c.Y < l.Height
+}
+
+// Index is the square c is, which must be on the board.
+func (l *Layout) Index(c api.Coord) int {
+	return c.Y*l.Width + c.X
+}
+
+// Coord is the inverse of Index.
+func (l *Layout) Coord(i int) api.Coord {
+	return api.Coord{X: i % l.Width, Y: i / l.Width}
+}
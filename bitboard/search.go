@@ -0,0 +1,135 @@
+package bitboard
+
+import (
+	"github.com/Xe/bsnk/api"
+)
+
+// Moves appends to buf the moves snake i can make next turn without running
+// into a snake or off the board, in the order of api.Directions. A tail is
+// fine to move into, since it moves out of the way first, unless it is
+// stacked. A dead snake has no moves.
+func (s *State) Moves(i int, buf []api.Direction) []api.Direction {
+	sn := &s.Snakes[i]
+	if !sn.Alive || sn.Head() < 0 {
+		return buf
+	}
+
+	for d, n := range s.Neighbors[sn.Head()] {
+		if n >= 0 && (!s.Occupied.Has(n) || s.occupants[n] == 1 && s.isTail(n)) {
+			buf = append(buf, api.Direction(d+1))
+		}
+	}
+
+	return buf
+}
+
+// isTail reports whether square c is the tail of a live snake.
+func (s *State) isTail(c int) bool {
+	for i := range s.Snakes {
+		if s.Snakes[i].Alive && s.Snakes[i].Tail() == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Territory is which snake gets to each square of a State first, worked out
+// the same way as for an api.Board. It can be reused for many states without
+// allocating.
+type Territory struct {
+	api.Territory
+
+	heads, lengths []int
+}
+
+// Compute works out which snake gets to each square of s first, replacing
+// what t held.
+func (t *Territory) Compute(s *State) {
+	t.heads, t.lengths = t.heads[:0], t.lengths[:0]
+	for i := range s.Snakes {
+		sn := &s.Snakes[i]
+		head := sn.Head()
+		if !sn.Alive {
+			head = -1
+		}
+
+		t.heads = append(t.heads, head)
+		t.lengths = append(t.lengths, sn.Length)
+	}
+
+	t.Width, t.Height = s.Width, s.Height
+	t.ComputeCells(api.Cells{
+		Neighbors: s.Neighbors,
+		Heads:     t.heads,
+		Lengths:   t.lengths,
+		Clears:    s.clearsInto,
+	})
+
+	for c, owner := range t.Owner {
+		if owner >= 0 && s.Food.Has(c) {
+			t.Food[owner]++
+		}
+	}
+}
+
+// clearsInto fills clears with how many turns until each square is free to
+// move into, like api.Board.ClearsAt.
+func (s *State) clearsInto(clears []int) {
+	for i := range s.Snakes {
+		sn := &s.Snakes[i]
+		if !sn.Alive {
+			continue
+		}
+
+		for j := 0; j < sn.Length; j++ {
+			if c := sn.At(j); c >= 0 && sn.Length-j > clears[c] {
+				clears[c] = sn.Length - j
+			}
+		}
+	}
+}
+
+// Distances is how many turns it takes one snake to get to each square of a
+// State, counting on bodies moving out of the way, like api.Board.Reachable.
+// It is the territory of the snake as if it were alone, and can be reused
+// for many states without allocating.
+type Distances struct {
+	// Turns is, for every square, how many turns it takes to get there, or
+	// -1 if the snake can't. The head is 0.
+	Turns []int
+
+	// Reached is how many squares the snake can get to, not counting where
+	// its head already is.
+	Reached int
+
+	alone          api.Territory
+	heads, lengths [1]int
+}
+
+// Compute works out how far snake i is from each square of s, replacing
+// what d held.
+func (d *Distances) Compute(s *State, i int) {
+	sn := &s.Snakes[i]
+	d.heads[0], d.lengths[0] = sn.Head(), sn.Length
+	if !sn.Alive {
+		d.heads[0] = -1
+	}
+
+	d.alone.ComputeCells(api.Cells{
+		Neighbors: s.Neighbors,
+		Heads:     d.heads[:],
+		Lengths:   d.lengths[:],
+		Clears:    s.clearsInto,
+	})
+
+	d.Turns, d.Reached = d.alone.Turns, 0
+	for c, owner := range d.alone.Owner {
+		switch {
+		case owner == api.Unreached:
+			d.Turns[c] = -1
+		case d.Turns[c] > 0:
+			d.Reached++
+		}
+	}
+}
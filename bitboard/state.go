@@ -0,0 +1,492 @@
+// Package bitboard is a compact game state for brains that look many moves
+// ahead. Squares are ints, occupancy and food are bitsets, bodies are ring
+// buffers, and moves are made and unmade in place so a search can walk a
+// game tree without allocating.
+//
+// Moves follow the standard rules (see package rules) up to and including
+// feeding; food never spawns, since a search can't know where it will.
+package bitboard
+
+import (
+	"github.com/Xe/bsnk/api"
+)
+
+// MaxHealth is the health a snake has after eating.
+const MaxHealth = 100
+
+// Snake is one snake in a State.
+type Snake struct {
+	ID     string
+	Name   string
+	Health int
+	Alive  bool
+
+	// Length is how many segments the snake has, counting a stacked tail
+	// more than once.
+	Length int
+
+	// body is a ring buffer of squares; the head is body[head], and the
+	// rest of the snake follows it around the ring.
+	body []int
+	head int
+}
+
+// At is the square the snake's i-th segment is in, counting from the head.
+func (sn *Snake) At(i int) int {
+	return sn.body[(sn.head+i)%len(sn.body)]
+}
+
+// Head is the square the snake's head is in, or -1 if it went off the board.
+func (sn *Snake) Head() int {
+	return sn.body[sn.head]
+}
+
+// Tail is the square the snake's tail is in.
+func (sn *Snake) Tail() int {
+	return sn.At(sn.Length - 1)
+}
+
+// grow stacks a segment on the tail.
+func (sn *Snake) grow() {
+	if sn.Length == len(sn.body) {
+		body := make([]int, 2*len(sn.body))
+		for i := 0; i < sn.Length; i++ {
+			body[i] = sn.At(i)
+		}
+		sn.body, sn.head = body, 0
+	}
+
+	sn.body[(sn.head+sn.Length)%len(sn.body)] = sn.Tail()
+	sn.Length++
+}
+
+// State is a game in progress.
+type State struct {
+	*Layout
+
+	Game api.Game
+	Turn int
+
+	// Snakes are in the order they were on the board. Eliminated snakes
+	// stay, so indexes don't change as moves are made and unmade.
+	Snakes []Snake
+
+	// You is the index of the snake the request was for, or -1.
+	You int
+
+	Food    Bitset
+	Hazards Bitset
+
+	// HazardDamage is how much health a turn in a hazard costs.
+	HazardDamage int
+
+	// Hash is the Zobrist hash of the position, kept up to date as moves
+	// are made and unmade.
+	Hash uint64
+
+	// Occupied has every square a live snake is in.
+	Occupied Bitset
+
+	// occupants is how many segments are in each square.
+	occupants []uint8
+
+	// undo holds, for every move made, what every snake was like before,
+	// and hashes what the hash was.
+	undo   []snakeUndo
+	hashes []uint64
+
+	// dying is scratch space for eliminations.
+	dying []bool
+}
+
+// snakeUndo is what it takes to unmake a move for one snake.
+type snakeUndo struct {
+	oldTail int
+	health  int
+	alive   bool
+	died    bool
+	ate     bool
+}
+
+// FromRequest converts a request into a State.
+func FromRequest(sr api.SnakeRequest) *State {
+	b := sr.Board
+	l := LayoutFor(b.Width, b.Height)
+
+	s := &State{
+		Layout:       l,
+		Game:         sr.Game,
+		Turn:         sr.Turn,
+		Snakes:       make([]Snake, len(b.Snakes)),
+		You:          -1,
+		Food:         NewBitset(l.Cells),
+		Hazards:      NewBitset(l.Cells),
+		HazardDamage: sr.Game.Ruleset.Settings.HazardDamagePerTurn,
+		Occupied:     NewBitset(l.Cells),
+		occupants:    make([]uint8, l.Cells),
+		dying:        make([]bool, len(b.Snakes)),
+	}
+
+	for _, c := range b.Food {
+		if l.Inside(c) {
+			s.Food.Set(l.Index(c))
+		}
+	}
+
+	for _, c := range b.Hazards {
+		if l.Inside(c) {
+			s.Hazards.Set(l.Index(c))
+		}
+	}
+
+	for i, sn := range b.Snakes {
+		if sn.ID == sr.You.ID {
+			s.You = i
+		}
+
+		// room to grow onto most of the board before the ring has to be
+		// made bigger
+		body := make([]int, l.Cells+len(sn.Body)+1)
+		for j, c := range sn.Body {
+			body[j] = -1
+			if l.Inside(c) {
+				body[j] = l.Index(c)
+			}
+		}
+
+		s.Snakes[i] = Snake{
+			ID:     sn.ID,
+			Name:   sn.Name,
+			Health: sn.Health,
+			Alive:  len(sn.Body) > 0,
+			Length: len(sn.Body),
+			body:   body,
+		}
+
+		if s.Snakes[i].Alive {
+			s.occupy(&s.Snakes[i])
+		}
+	}
+	s.Hash = s.ComputeHash()
+
+	return s
+}
+
+// Clone makes a copy of s that can be changed separately, such as by
+// another goroutine. Moves made on s can't be unmade on the copy.
+func (s *State) Clone() *State {
+	result := *s
+	result.Snakes = make([]Snake, len(s.Snakes))
+	for i, sn := range s.Snakes {
+		sn.body = append([]int(nil), sn.body...)
+		result.Snakes[i] = sn
+	}
+
+	result.Food = append(Bitset(nil), s.Food...)
+	result.Hazards = append(Bitset(nil), s.Hazards...)
+	result.Occupied = append(Bitset(nil), s.Occupied...)
+	result.occupants = append([]uint8(nil), s.occupants...)
+	result.undo = nil
+	result.hashes = nil
+	result.dying = make([]bool, len(s.Snakes))
+
+	return &result
+}
+
+// Board converts s back into a board, with only the snakes still alive.
+func (s *State) Board() api.Board {
+	b := api.Board{
+		Width:  s.Width,
+		Height: s.Height,
+		Food:   []api.Coord{},
+		Snakes: []api.Snake{},
+	}
+
+	s.Food.Each(func(i int) { b.Food = append(b.Food, s.Coord(i)) })
+	s.Hazards.Each(func(i int) { b.Hazards = append(b.Hazards, s.Coord(i)) })
+
+	for i := range s.Snakes {
+		if s.Snakes[i].Alive {
+			b.Snakes = append(b.Snakes, s.snake(i))
+		}
+	}
+
+	return b
+}
+
+// Request converts s back into a request for the snake at index you.
+func (s *State) Request(you int) api.SnakeRequest {
+	sr := api.SnakeRequest{
+		Game:  s.Game,
+		Turn:  s.Turn,
+		Board: s.Board(),
+	}
+
+	if you >= 0 && you < len(s.Snakes) {
+		sr.You = s.snake(you)
+	}
+
+	return sr
+}
+
+func (s *State) snake(i int) api.Snake {
+	sn := &s.Snakes[i]
+	result := api.Snake{
+		ID:     sn.ID,
+		Name:   sn.Name,
+		Health: sn.Health,
+		Length: sn.Length,
+		Body:   make([]api.Coord, sn.Length),
+	}
+
+	for j := range result.Body {
+		if c := sn.At(j); c >= 0 {
+			result.Body[j] = s.Coord(c)
+		}
+	}
+	result.Head = result.Body[0]
+
+	return result
+}
+
+// IsDeadly reports whether moving into square c would be deadly right now,
+// like api.Board.IsDeadly. Squares off the board are -1.
+func (s *State) IsDeadly(c int) bool {
+	return c < 0 || s.Occupied.Has(c)
+}
+
+// Alive is how many snakes are still alive.
+func (s *State) Alive() int {
+	n := 0
+	for i := range s.Snakes {
+		if s.Snakes[i].Alive {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Depth is how many moves have been made that can be unmade.
+func (s *State) Depth() int {
+	return len(s.hashes)
+}
+
+// Make moves every snake still alive, with moves in the same order as
+// Snakes. Snakes without a valid move keep going the way they were going,
+// as the engine does. It can be undone with Unmake.
+func (s *State) Make(moves []api.Direction) {
+	s.Turn++
+	s.hashes = append(s.hashes, s.Hash)
+	z := s.keys
+
+	for i := range s.Snakes {
+		sn := &s.Snakes[i]
+		u := snakeUndo{health: sn.Health, alive: sn.Alive}
+
+		if sn.Alive {
+			var dir api.Direction
+			if i < len(moves) {
+				dir = moves[i]
+			}
+
+			next := -1
+			if head := sn.Head(); head >= 0 {
+				next = s.Neighbors[head][s.moveFor(sn, dir)-1]
+			}
+
+			// the tail moves out of the way; it is kept in case the snake
+			// eats, which writes over where it was in the ring
+			u.oldTail = sn.Tail()
+			s.leave(u.oldTail)
+			s.Hash ^= z.bodyKey(i, u.oldTail) ^ z.headKey(i, sn.Head())
+			sn.head = (sn.head + len(sn.body) - 1) % len(sn.body)
+			sn.body[sn.head] = next
+			s.enter(next)
+			s.Hash ^= z.bodyKey(i, next) ^ z.headKey(i, next)
+
+			sn.Health--
+			if next >= 0 && s.HazardDamage > 0 && s.Hazards.Has(next) && !s.Food.Has(next) {
+				sn.Health -= s.HazardDamage
+				if sn.Health < 0 {
+					sn.Health = 0
+				}
+			}
+			s.Hash ^= z.healthKey(i, u.health) ^ z.healthKey(i, sn.Health)
+		}
+
+		s.undo = append(s.undo, u)
+	}
+
+	undo := s.undo[len(s.undo)-len(s.Snakes):]
+	s.feed(undo)
+	s.eliminate(undo)
+}
+
+// moveFor is the move sn makes when asked to go dir.
+func (s *State) moveFor(sn *Snake, dir api.Direction) api.Direction {
+	if dir.Valid() {
+		return dir
+	}
+
+	if sn.Length >= 2 {
+		if neck := sn.At(1); neck >= 0 {
+			for d, n := range s.Neighbors[neck] {
+				if n == sn.Head() {
+					return api.Direction(d + 1)
+				}
+			}
+		}
+	}
+
+	return api.Up
+}
+
+// eliminate removes snakes that starved, left the board or ran into
+// something, in the same two rounds as the rules do.
+func (s *State) eliminate(undo []snakeUndo) {
+	for i := range s.Snakes {
+		sn := &s.Snakes[i]
+		if sn.Alive && (sn.Health <= 0 || sn.Head() < 0) {
+			s.kill(i, undo)
+		}
+	}
+
+	for i := range s.Snakes {
+		sn := &s.Snakes[i]
+		s.dying[i] = false
+		if !sn.Alive {
+			continue
+		}
+
+		head := sn.Head()
+		heads := 0
+		for j := range s.Snakes {
+			other := &s.Snakes[j]
+			if !other.Alive || other.Head() != head {
+				continue
+			}
+
+			heads++
+			if j != i && sn.Length <= other.Length {
+				s.dying[i] = true
+			}
+		}
+
+		// anything in the square other than heads is a body
+		if int(s.occupants[head]) > heads {
+			s.dying[i] = true
+		}
+	}
+
+	for i := range s.Snakes {
+		if s.dying[i] {
+			s.kill(i, undo)
+		}
+	}
+}
+
+func (s *State) kill(i int, undo []snakeUndo) {
+	s.Hash ^= s.snakeHash(i)
+	s.Snakes[i].Alive = false
+	undo[i].died = true
+	s.vacate(&s.Snakes[i])
+}
+
+// feed has snakes whose heads are on food eat it. Like the rules, it comes
+// before eliminations, so a snake that runs out of health on food lives.
+func (s *State) feed(undo []snakeUndo) {
+	for i := range s.Snakes {
+		sn := &s.Snakes[i]
+		if !sn.Alive || sn.Head() < 0 || !s.Food.Has(sn.Head()) {
+			continue
+		}
+
+		z := s.keys
+		s.Hash ^= z.healthKey(i, sn.Health) ^ z.healthKey(i, MaxHealth)
+		s.Hash ^= z.lengthKey(i, sn.Length) ^ z.lengthKey(i, sn.Length+1)
+
+		undo[i].ate = true
+		sn.Health = MaxHealth
+		sn.grow()
+		s.enter(sn.Tail())
+		s.Hash ^= z.bodyKey(i, sn.Tail())
+	}
+
+	// food is taken away after everyone on it has eaten
+	for i := range s.Snakes {
+		if head := s.Snakes[i].Head(); undo[i].ate && s.Food.Has(head) {
+			s.Food.Clear(head)
+			s.Hash ^= s.keys.food[head]
+		}
+	}
+}
+
+// Unmake undoes the last move made.
+func (s *State) Unmake() {
+	undo := s.undo[len(s.undo)-len(s.Snakes):]
+	s.Turn--
+
+	for i := range s.Snakes {
+		sn := &s.Snakes[i]
+		u := undo[i]
+		if !u.alive {
+			continue
+		}
+
+		if u.died {
+			sn.Alive = true
+			s.occupy(sn)
+		}
+
+		if u.ate {
+			s.Food.Set(sn.Head())
+			s.leave(sn.Tail())
+			sn.Length--
+		}
+
+		s.leave(sn.Head())
+		sn.head = (sn.head + 1) % len(sn.body)
+		sn.body[(sn.head+sn.Length-1)%len(sn.body)] = u.oldTail
+		s.enter(u.oldTail)
+		sn.Health = u.health
+	}
+
+	s.undo = s.undo[:len(s.undo)-len(s.Snakes)]
+	s.Hash = s.hashes[len(s.hashes)-1]
+	s.hashes = s.hashes[:len(s.hashes)-1]
+}
+
+// occupy adds every segment of sn to the board.
+func (s *State) occupy(sn *Snake) {
+	for j := 0; j < sn.Length; j++ {
+		s.enter(sn.At(j))
+	}
+}
+
+// vacate takes every segment of sn off the board.
+func (s *State) vacate(sn *Snake) {
+	for j := 0; j < sn.Length; j++ {
+		s.leave(sn.At(j))
+	}
+}
+
+func (s *State) enter(c int) {
+	if c < 0 {
+		return
+	}
+
+	s.occupants[c]++
+	s.Occupied.Set(c)
+}
+
+func (s *State) leave(c int) {
+	if c < 0 {
+		return
+	}
+
+	s.occupants[c]--
+	if s.occupants[c] == 0 {
+		s.Occupied.Clear(c)
+	}
+}
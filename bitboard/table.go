@@ -0,0 +1,228 @@
+package bitboard
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/Xe/bsnk/api"
+)
+
+// Bound says what a score in a transposition table is.
+type Bound uint8
+
+// Kinds of Bound.
+const (
+	// Exact scores are the position's score.
+	Exact Bound = iota
+
+	// Lower scores are at least the position's score, from a search that
+	// was cut off because it found something too good.
+	Lower
+
+	// Upper scores are at most the position's score, from a search that
+	// didn't find anything good enough.
+	Upper
+)
+
+// Entry is what a search remembers about a position.
+type Entry struct {
+	// Score is what the position was worth, as Bound says.
+	Score float32
+
+	// Depth is how far the search that scored it looked ahead.
+	Depth int
+
+	Bound Bound
+
+	// Move is the best move found, if any.
+	Move api.Direction
+
+	// age is the table's age when the entry was stored.
+	age uint8
+}
+
+// pack squeezes an entry into 64 bits: the score in the low 32, then 8 bits
+// of depth, 2 of bound, 3 of move and 8 of age. The top bit is always set,
+// so no entry packs to the zero an empty slot holds.
+func (e Entry) pack() uint64 {
+	depth := e.Depth
+	if depth > 255 {
+		depth = 255
+	}
+	if depth < 0 {
+		depth = 0
+	}
+
+	return uint64(math.Float32bits(e.Score)) |
+		uint64(depth)<<32 |
+		uint64(e.Bound&3)<<40 |
+		uint64(e.Move&7)<<42 |
+		uint64(e.age)<<45 |
+		1<<63
+}
+
+func unpack(data uint64) Entry {
+	return Entry{
+		Score: math.Float32frombits(uint32(data)),
+		Depth: int(data >> 32 & 0xff),
+		Bound: Bound(data >> 40 & 3),
+		Move:  api.Direction(data >> 42 & 7),
+		age:   uint8(data >> 45),
+	}
+}
+
+// Policy is how a transposition table decides what to keep when two
+// positions want the same slot.
+type Policy int
+
+// Kinds of Policy.
+const (
+	// TwoTier gives every position two slots: one keeps whichever entry
+	// looked deepest, unless it is from an older search, and the other
+	// always takes the newest entry.
+	TwoTier Policy = iota
+
+	// DepthPreferred keeps whichever entry looked deepest, unless it is from
+	// an older search.
+	DepthPreferred
+
+	// AlwaysReplace keeps the newest entry.
+	AlwaysReplace
+)
+
+// slot holds one entry. Key is the position's hash xored with the packed
+// entry, so an entry torn by two goroutines writing it at once doesn't
+// match any hash and reads as missing instead of as garbage.
+type slot struct {
+	key  uint64
+	data uint64
+}
+
+// Table is a fixed size transposition table: a cache of search results by
+// position hash, so the same position reached by different moves, or again
+// on a later turn, doesn't have to be searched twice. It is safe for
+// concurrent use without locks.
+type Table struct {
+	Policy Policy
+
+	slots []slot
+	mask  uint64
+	age   uint32
+}
+
+// NewTable makes a table with room for about size entries.
+func NewTable(size int, policy Policy) *Table {
+	n := 2
+	for n*2 <= size {
+		n *= 2
+	}
+
+	return &Table{
+		Policy: policy,
+		slots:  make([]slot, n),
+		mask:   uint64(n - 1),
+	}
+}
+
+// Age starts a new search, such as for the next turn. Entries from older
+// searches can still be found, but are the first to be replaced.
+func (t *Table) Age() {
+	atomic.AddUint32(&t.age, 1)
+}
+
+// Clear forgets everything.
+func (t *Table) Clear() {
+	for i := range t.slots {
+		atomic.StoreUint64(&t.slots[i].key, 0)
+		atomic.StoreUint64(&t.slots[i].data, 0)
+	}
+}
+
+// bucket is the index of the first of the two slots hash can go in.
+func (t *Table) bucket(hash uint64) uint64 {
+	return hash & t.mask &^ 1
+}
+
+func (t *Table) load(i uint64) (key, data uint64) {
+	return atomic.LoadUint64(&t.slots[i].key), atomic.LoadUint64(&t.slots[i].data)
+}
+
+func (t *Table) store(i, hash uint64, data uint64) {
+	atomic.StoreUint64(&t.slots[i].key, hash^data)
+	atomic.StoreUint64(&t.slots[i].data, data)
+}
+
+// Probe looks up the entry for a position.
+func (t *Table) Probe(hash uint64) (Entry, bool) {
+	b := t.bucket(hash)
+	for i := b; i < b+2; i++ {
+		key, data := t.load(i)
+		if key^data == hash && data != 0 {
+			return unpack(data), true
+		}
+	}
+
+	return Entry{}, false
+}
+
+// Store remembers an entry for a position, if the policy says it is worth
+// more than what it would replace.
+func (t *Table) Store(hash uint64, e Entry) {
+	age := uint8(atomic.LoadUint32(&t.age))
+	e.age = age
+	data := e.pack()
+
+	b := t.bucket(hash)
+	if t.Policy == AlwaysReplace {
+		// the hash's top bit picks a slot, so both get used
+		t.store(b+hash>>63, hash, data)
+		return
+	}
+
+	// a position already in the table is updated where it is
+	for i := b; i < b+2; i++ {
+		key, old := t.load(i)
+		if key^old == hash && old != 0 {
+			if t.Policy == TwoTier && i == b+1 || replaces(unpack(old), e, age) {
+				t.store(i, hash, data)
+			} else if t.Policy == TwoTier {
+				t.store(b+1, hash, data)
+			}
+			return
+		}
+	}
+
+	_, first := t.load(b)
+	if t.Policy == TwoTier {
+		if first == 0 || replaces(unpack(first), e, age) {
+			t.store(b, hash, data)
+		} else {
+			t.store(b+1, hash, data)
+		}
+		return
+	}
+
+	// DepthPreferred: whichever of the two is the least worth keeping
+	victim, old := b, first
+	if _, second := t.load(b + 1); worse(unpack(second), unpack(first), age) {
+		victim, old = b+1, second
+	}
+	if old == 0 || replaces(unpack(old), e, age) {
+		t.store(victim, hash, data)
+	}
+}
+
+// replaces reports whether e is worth more than old, which it is if old is
+// from an older search or looked no further ahead.
+func replaces(old, e Entry, age uint8) bool {
+	return old.age != age || e.Depth >= old.Depth
+}
+
+// worse reports whether a is less worth keeping than b.
+func worse(a, b Entry, age uint8) bool {
+	if (a.age == age) != (b.age == age) {
+		return a.age != age
+	}
+
+	return a.Depth < b.Depth
+}
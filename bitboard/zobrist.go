@@ -0,0 +1,138 @@
+package bitboard
+
+// Zobrist hashing gives every feature of a position a random number and
+// hashes a position by xoring together the numbers of the features it has.
+// Making a move only changes a few features, so the hash can be kept up to
+// date as moves are made instead of being worked out again every time.
+
+const (
+	// HealthBucket is how much health is hashed as the same. Positions
+	// that only differ by a little health are close enough to reuse the
+	// same search results for.
+	HealthBucket = 10
+
+	// hashedSnakes is how many snakes have keys of their own. Snakes past
+	// that share keys, which makes collisions more likely but is otherwise
+	// harmless.
+	hashedSnakes = 16
+
+	// hashedLengths is how many lengths have keys of their own; longer
+	// snakes share the last one.
+	hashedLengths = 256
+)
+
+// zobristKeys are the random numbers for every feature of a position on a
+// board of some size.
+type zobristKeys struct {
+	head   [hashedSnakes][]uint64
+	body   [hashedSnakes][]uint64
+	health [hashedSnakes][MaxHealth/HealthBucket + 1]uint64
+	length [hashedSnakes][hashedLengths]uint64
+	food   []uint64
+	hazard []uint64
+}
+
+// newZobristKeys makes the keys for a board with cells squares. They are
+// the same every time, so hashes can be compared between processes.
+func newZobristKeys(cells int) *zobristKeys {
+	rng := splitmix64(uint64(cells))
+	fill := func(keys []uint64) []uint64 {
+		for i := range keys {
+			keys[i] = rng()
+		}
+
+		return keys
+	}
+
+	z := &zobristKeys{
+		food:   fill(make([]uint64, cells)),
+		hazard: fill(make([]uint64, cells)),
+	}
+
+	for i := 0; i < hashedSnakes; i++ {
+		z.head[i] = fill(make([]uint64, cells))
+		z.body[i] = fill(make([]uint64, cells))
+		fill(z.health[i][:])
+		fill(z.length[i][:])
+	}
+
+	return z
+}
+
+// splitmix64 is a small, fast random number generator that is good enough
+// for hash keys.
+func splitmix64(seed uint64) func() uint64 {
+	return func() uint64 {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+
+		return z ^ (z >> 31)
+	}
+}
+
+func (z *zobristKeys) healthKey(snake, health int) uint64 {
+	if health < 0 {
+		health = 0
+	}
+	if health > MaxHealth {
+		health = MaxHealth
+	}
+
+	return z.health[snake%hashedSnakes][health/HealthBucket]
+}
+
+func (z *zobristKeys) lengthKey(snake, length int) uint64 {
+	if length >= hashedLengths {
+		length = hashedLengths - 1
+	}
+
+	return z.length[snake%hashedSnakes][length]
+}
+
+// bodyKey is the key for a segment of a snake. Squares off the board have
+// no key.
+func (z *zobristKeys) bodyKey(snake, c int) uint64 {
+	if c < 0 {
+		return 0
+	}
+
+	return z.body[snake%hashedSnakes][c]
+}
+
+func (z *zobristKeys) headKey(snake, c int) uint64 {
+	if c < 0 {
+		return 0
+	}
+
+	return z.head[snake%hashedSnakes][c]
+}
+
+// snakeHash is the part of the hash for one live snake.
+func (s *State) snakeHash(i int) uint64 {
+	z, sn := s.keys, &s.Snakes[i]
+
+	h := z.headKey(i, sn.Head()) ^ z.healthKey(i, sn.Health) ^ z.lengthKey(i, sn.Length)
+	for j := 0; j < sn.Length; j++ {
+		h ^= z.bodyKey(i, sn.At(j))
+	}
+
+	return h
+}
+
+// ComputeHash works out the hash of s from scratch. Hash is always the same
+// as what it returns; this is for checking that.
+func (s *State) ComputeHash() uint64 {
+	var h uint64
+	for i := range s.Snakes {
+		if s.Snakes[i].Alive {
+			h ^= s.snakeHash(i)
+		}
+	}
+
+	s.Food.Each(func(c int) { h ^= s.keys.food[c] })
+	s.Hazards.Each(func(c int) { h ^= s.keys.hazard[c] })
+
+	return h
+}
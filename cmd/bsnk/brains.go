@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/snakes"
+)
+
+// config is the snakes this binary runs, loaded from -config.
+var config *Config
+
+// lookupBrain makes a fresh instance of the snake with the given name, so
+// stateful brains don't share state. Snakes from the config come first, then
+// brains by the name they registered with. Anything that looks like a URL is
+// treated as a remote snake.
+func lookupBrain(name string) (api.AI, error) {
+	if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+		return api.Client{URL: name}, nil
+	}
+
+	if sc, ok := config.snake(name); ok {
+		return sc.make()
+	}
+
+	if b, ok := snakes.Lookup(name); ok {
+		return b.Make(nil)
+	}
+
+	return nil, fmt.Errorf("unknown snake %q, known snakes: %s, known brains: %s",
+		name, strings.Join(config.names(), ", "), strings.Join(snakes.Names(), ", "))
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/snakes"
+)
+
+var (
+	configPath = flag.String("config", "", "if set, mount the snakes declared in this JSON file instead of one of every brain")
+
+	// pyraMinLength is from before snakes had settings. It only still does
+	// anything without -config, so existing deployments keep working.
+	pyraMinLength = flag.Int("pyra-min-length", 0, "deprecated, use pyra's min_length setting in -config; if set, pyra's min_length in the default config")
+)
+
+// Config declares which snakes to run. Each snake is a brain from package
+// snakes, and the same brain can be run more than once with different
+// settings:
+//
+//	{
+//	  "snakes": [
+//	    {"name": "pyra"},
+//	    {"name": "pyra-hungry", "brain": "pyra", "color": "#ff7f50", "settings": {"min_length": 20}}
+//	  ]
+//	}
+type Config struct {
+	Snakes []SnakeConfig `json:"snakes"`
+}
+
+// SnakeConfig is one snake in a Config.
+type SnakeConfig struct {
+	// Name is what the snake is called. It is served at /<name>/.
+	Name string `json:"name"`
+
+	// Brain is the registered brain to run. If empty, it is the name.
+	Brain string `json:"brain,omitempty"`
+
+	// Color, Head and Tail override what the brain answers pings with.
+	Color string `json:"color,omitempty"`
+	Head  string `json:"head,omitempty"`
+	Tail  string `json:"tail,omitempty"`
+
+	// Settings are decoded over the brain's default settings.
+	Settings json.RawMessage `json:"settings,omitempty"`
+}
+
+// snakeName is what snake names can look like, so they are safe to use in
+// paths and metric labels.
+var snakeName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// reservedNames are paths serve already uses for something else.
+var reservedNames = map[string]bool{
+	"admin":   true,
+	"debug":   true,
+	"games":   true,
+	"health":  true,
+	"metrics": true,
+	"vars":    true,
+}
+
+// defaultConfig runs one of every registered brain with its default
+// settings, named after the brain, other than what -pyra-min-length says.
+func defaultConfig() *Config {
+	c := &Config{}
+	for _, name := range snakes.Names() {
+		sc := SnakeConfig{Name: name}
+		if name == "pyra" && *pyraMinLength != 0 {
+			sc.Settings = json.RawMessage(fmt.Sprintf(`{"min_length": %d}`, *pyraMinLength))
+		}
+
+		c.Snakes = append(c.Snakes, sc)
+	}
+
+	return c
+}
+
+// loadConfig reads the config at path, or makes the default one if path is
+// empty.
+func loadConfig(path string) (*Config, error) {
+	if path == "" {
+		return defaultConfig(), nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// validate makes sure every snake in c can be run, so mistakes show up when
+// the config is loaded instead of when a game starts.
+func (c *Config) validate() error {
+	if len(c.Snakes) == 0 {
+		return fmt.Errorf("no snakes")
+	}
+
+	seen := map[string]bool{}
+	for _, sc := range c.Snakes {
+		switch {
+		case !snakeName.MatchString(sc.Name):
+			return fmt.Errorf("snake name %q must be lowercase letters, digits, - and _", sc.Name)
+		case reservedNames[sc.Name]:
+			return fmt.Errorf("snake name %q is reserved", sc.Name)
+		case seen[sc.Name]:
+			return fmt.Errorf("snake name %q is used more than once", sc.Name)
+		}
+		seen[sc.Name] = true
+
+		if _, err := sc.make(); err != nil {
+			return fmt.Errorf("snake %s: %w", sc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// snake finds the snake with the given name.
+func (c *Config) snake(name string) (SnakeConfig, bool) {
+	for _, sc := range c.Snakes {
+		if sc.Name == name {
+			return sc, true
+		}
+	}
+
+	return SnakeConfig{}, false
+}
+
+// names lists the snakes in c in the order they were declared.
+func (c *Config) names() []string {
+	var result []string
+	for _, sc := range c.Snakes {
+		result = append(result, sc.Name)
+	}
+
+	return result
+}
+
+func (sc SnakeConfig) brain() string {
+	if sc.Brain == "" {
+		return sc.Name
+	}
+
+	return sc.Brain
+}
+
+// make makes a fresh instance of the snake.
+func (sc SnakeConfig) make() (api.AI, error) {
+	b, ok := snakes.Lookup(sc.brain())
+	if !ok {
+		return nil, fmt.Errorf("unknown brain %q, known brains: %v", sc.brain(), snakes.Names())
+	}
+
+	ai, err := b.Make(sc.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	if n, ok := ai.(api.Namer); ok {
+		n.SetName(sc.Name)
+	}
+
+	if sc.Color == "" && sc.Head == "" && sc.Tail == "" {
+		return ai, nil
+	}
+
+	return styled{AI: ai, sc: sc}, nil
+}
+
+// styled dresses a brain up the way its config says to.
+type styled struct {
+	api.AI
+	sc SnakeConfig
+}
+
+func (s styled) Ping() (*api.PingResponse, error) {
+	pr, err := s.AI.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	// don't scribble on what the brain answered with
+	result := *pr
+	if s.sc.Color != "" {
+		result.Color = s.sc.Color
+	}
+	if s.sc.Head != "" {
+		result.HeadType = s.sc.Head
+	}
+	if s.sc.Tail != "" {
+		result.TailType = s.sc.Tail
+	}
+
+	return &result, nil
+}
+
+// Debug shows the state of the brain being dressed up.
+func (s styled) Debug() interface{} {
+	state, _ := api.DebugState(s.AI)
+	return state
+}
+
+// Retire retires the brain being dressed up.
+func (s styled) Retire() {
+	api.Retire(s.AI)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Xe/bsnk/snakes"
+)
+
+func TestPyraMinLength(t *testing.T) {
+	defer func(old int) { *pyraMinLength = old }(*pyraMinLength)
+	*pyraMinLength = 12
+
+	sc, ok := defaultConfig().snake("pyra")
+	if !ok {
+		t.Fatal("the default config should have pyra")
+	}
+
+	ai, err := sc.make()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ai.(*snakes.Pyra).MinLength; got != 12 {
+		t.Errorf("-pyra-min-length should set pyra's min_length, got: %d", got)
+	}
+}
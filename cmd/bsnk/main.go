@@ -2,14 +2,15 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
+	"fmt"
 	"net/http"
 	"path/filepath"
 	"time"
 
 	"github.com/Xe/bsnk/api"
-	"github.com/Xe/bsnk/snakes"
+	"github.com/Xe/bsnk/render"
+	"github.com/Xe/bsnk/viewer"
 	"github.com/facebookgo/flagenv"
 	"github.com/povilasv/prommod"
 	"github.com/prometheus/client_golang/prometheus"
@@ -20,12 +21,6 @@ import (
 	"within.website/ln/opname"
 )
 
-func index(res http.ResponseWriter, req *http.Request) {
-	res.WriteHeader(http.StatusOK)
-	res.Header().Set("Content-Type", "text/html")
-	res.Write([]byte("<p>Battlesnake documentation can be found at <a href=\"https://docs.battlesnake.io\">https://docs.battlesnake.io</a>.</p>"))
-}
-
 func health(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "OK", http.StatusOK)
 }
@@ -34,10 +29,9 @@ func middlewareSpan(family string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		sp := trace.New(family, filepath.Base(r.URL.Path))
 		defer sp.Finish()
-		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
-		defer cancel()
 
-		ctx = trace.NewContext(ctx, sp)
+		// api.Server gives brains a deadline based on the game's timeout
+		ctx := trace.NewContext(r.Context(), sp)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -84,18 +78,24 @@ func middlewareMetrics(family string, next http.Handler) http.Handler {
 }
 
 var (
-	port          = flag.String("port", "5000", "http port to listen on")
-	gitRev        = flag.String("git-rev", "", "if set, use this git revision for the color code")
-	pyraMinLength = flag.Int("pyra-min-length", 8, "min length for pyra")
+	port       = flag.String("port", "5000", "http port to listen on")
+	gitRev     = flag.String("git-rev", "", "if set, use this git revision for the color code")
+	moveMargin = flag.Duration("move-margin", api.DefaultMargin, "how long before the engine's deadline moves are due")
+
+	recordDir      = flag.String("record-dir", "", "if set, record every game to this directory")
+	recordMaxGames = flag.Int("record-max-games", 1000, "most recorded games to keep per snake, 0 for no limit")
+	recordMaxAge   = flag.Duration("record-max-age", 7*24*time.Hour, "how long to keep recorded games, 0 for forever")
 )
 
-func createSnake(name string, ai api.AI) http.Handler {
-	return middlewareMetrics(name,
-		middlewareSpan(name, api.Server{
-			Brain: ai,
-			Name:  name,
-		}),
-	)
+func createSnake(name string, ai api.AI, rec *api.Recorder) (*api.Server, http.Handler) {
+	srv := &api.Server{
+		Brain:    ai,
+		Name:     name,
+		Recorder: rec,
+		Margin:   *moveMargin,
+	}
+
+	return srv, middlewareMetrics(name, middlewareSpan(name, srv))
 }
 
 func init() {
@@ -106,35 +106,73 @@ func init() {
 	}
 }
 
-func vars(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	e := json.NewEncoder(w)
-	e.SetIndent("", "  ")
-	e.Encode(map[string]interface{}{
-		"git_rev":         *gitRev,
-		"pyra_min_length": *pyraMinLength,
-	})
-}
-
 func main() {
 	flagenv.Parse()
 	flag.Parse()
 
 	ctx := opname.With(context.Background(), "main")
 
+	if *pyraMinLength != 0 {
+		ln.Log(ctx, ln.Info("-pyra-min-length is deprecated, use pyra's min_length setting in -config instead"), ln.F{
+			"pyra_min_length": *pyraMinLength,
+			"ignored":         *configPath != "",
+		})
+	}
+
+	var err error
+	config, err = loadConfig(*configPath)
+	if err != nil {
+		ln.FatalErr(ctx, err)
+	}
+
+	switch cmd := flag.Arg(0); cmd {
+	case "", "serve":
+		serve(ctx)
+	case "play":
+		err = play(opname.With(ctx, "play"), flag.Args()[1:])
+	case "tournament":
+		err = tournament(opname.With(ctx, "tournament"), flag.Args()[1:])
+	case "replay":
+		err = replay(opname.With(ctx, "replay"), flag.Args()[1:])
+	case "render":
+		err = renderCmd(opname.With(ctx, "render"), flag.Args()[1:])
+	default:
+		err = fmt.Errorf("unknown command %q, wanted serve, play, tournament, replay or render", cmd)
+	}
+
+	if err != nil {
+		ln.FatalErr(ctx, err)
+	}
+}
+
+func serve(ctx context.Context) {
 	prometheus.Register(prommod.NewCollector("bsnk"))
 
-	http.HandleFunc("/", index)
-	http.HandleFunc("/vars", vars)
+	var rec *api.Recorder
+	if *recordDir != "" {
+		rec = &api.Recorder{
+			Dir:      *recordDir,
+			MaxGames: *recordMaxGames,
+			MaxAge:   *recordMaxAge,
+		}
+	}
+
+	m, err := newMounts(*configPath, *adminToken, config, rec)
+	if err != nil {
+		ln.FatalErr(ctx, err)
+	}
+	m.next = viewer.Handler{
+		Dir:    *recordDir,
+		Brains: m,
+	}
+	go m.watch(ctx, *configPoll)
+
+	http.HandleFunc("/vars", vars(m))
 	http.Handle("/metrics", promhttp.Handler())
 	http.HandleFunc("/health", health)
-	http.Handle("/garen/", createSnake("garen", snakes.Garen{}))
-	http.Handle("/greedy/", createSnake("greedy", &snakes.Greedy{}))
-	http.Handle("/erratic/", createSnake("erratic", snakes.Erratic{}))
-	http.Handle("/pyra/", createSnake("pyra", &snakes.Pyra{
-		MinLength: *pyraMinLength,
-	}))
-	http.Handle("/sunset/", createSnake("sunset", snakes.Sunset{}))
+	http.HandleFunc("/admin/reload", m.admin)
+	http.Handle("/debug/render", m.adminOnly(render.Handler(m)))
+	http.Handle("/", m)
 
 	ln.Log(ctx, ln.Info("booting"))
 	ln.FatalErr(ctx, http.ListenAndServe(
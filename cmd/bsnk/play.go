@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/match"
+	"github.com/Xe/bsnk/render"
+	"github.com/Xe/bsnk/rules"
+	"within.website/ln"
+)
+
+const playUsage = `usage: bsnk play [flags] <snake> [snake...]
+
+Plays a game between the given snakes in-process. Snakes are either the name
+of a brain this binary knows about or the URL of a remote snake. Every turn
+and the final result are written to standard out as JSON lines, or with
+-render every turn is drawn as text. Set -record-dir before the play command
+to record the game for the game viewer.
+
+`
+
+func play(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("play", flag.ExitOnError)
+	width := fs.Int("width", 11, "board width")
+	height := fs.Int("height", 11, "board height")
+	ruleset := fs.String("ruleset", "standard", "ruleset to play with")
+	seed := fs.Int64("seed", 0, "random seed, if zero one is picked and reported")
+	timeout := fs.Duration("timeout", api.DefaultTimeout, "time each snake has to pick a move")
+	maxTurns := fs.Int("max-turns", 0, "end the game as a draw after this many turns, if set")
+	quiet := fs.Bool("quiet", false, "only print the result")
+	draw := fs.Bool("render", false, "draw every turn as text instead of printing it as JSON")
+	color := fs.Bool("color", false, "draw with ANSI colors, with -render")
+	gifPath := fs.String("gif", "", "if set, also draw the game as an animated GIF to this file")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), playUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no snakes given")
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	m := match.Match{
+		Ruleset: api.Ruleset{
+			Name:     *ruleset,
+			Settings: rules.DefaultSettings(),
+		},
+		Width:    *width,
+		Height:   *height,
+		Timeout:  *timeout,
+		Seed:     *seed,
+		MaxTurns: *maxTurns,
+	}
+
+	// with -record-dir, local games are recorded the same way served games
+	// are so they show up in the game viewer
+	var rec *api.Recorder
+	if *recordDir != "" {
+		rec = &api.Recorder{
+			Dir:      *recordDir,
+			MaxGames: *recordMaxGames,
+			MaxAge:   *recordMaxAge,
+		}
+		defer rec.Close()
+	}
+
+	for _, name := range fs.Args() {
+		ai, err := lookupBrain(name)
+		if err != nil {
+			return err
+		}
+
+		if rec != nil {
+			ai = rec.Wrap(name, ai)
+		}
+
+		m.Players = append(m.Players, match.Player{Name: name, AI: ai})
+	}
+
+	// snakes are drawn in the colors their brains say they are
+	ais := map[string]api.AI{}
+	for i, pl := range m.Players {
+		ais[match.SnakeID(i)] = pl.AI
+	}
+	colors := render.PingColors(ais)
+
+	var boards []api.Board
+	enc := json.NewEncoder(os.Stdout)
+	switch {
+	case *quiet:
+	case *draw:
+		m.OnTurn = func(t match.Turn) {
+			if t.Turn == 0 {
+				colors = render.Colors(t.Board, render.Options{Colors: colors})
+			}
+
+			fmt.Printf("turn %d\n%s\n", t.Turn, render.Text(t.Board, render.Options{
+				Color:  *color,
+				Colors: colors,
+				Axes:   true,
+				Legend: true,
+			}))
+		}
+	default:
+		m.OnTurn = func(t match.Turn) {
+			enc.Encode(t)
+		}
+	}
+
+	if *gifPath != "" {
+		onTurn := m.OnTurn
+		m.OnTurn = func(t match.Turn) {
+			boards = append(boards, t.Board)
+			if onTurn != nil {
+				onTurn(t)
+			}
+		}
+	}
+
+	result, err := m.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *gifPath != "" {
+		err := writeOut(*gifPath, func(w io.Writer) error {
+			return render.GIF(w, boards, render.Options{Colors: colors}, 150*time.Millisecond)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	ln.Log(ctx, result)
+	return enc.Encode(result)
+}
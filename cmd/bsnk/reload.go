@@ -0,0 +1,434 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"within.website/ln"
+)
+
+var (
+	configPoll = flag.Duration("config-poll", 5*time.Second, "how often to check -config for changes, 0 to only reload on SIGHUP or /admin/reload")
+	adminToken = flag.String("admin-token", "", "if set, POSTing to /admin/reload with this as a bearer token reloads the config, and /debug/render needs it too")
+)
+
+var (
+	configReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "config_reloads",
+		Help: "The number of times the config was reloaded, by whether it worked",
+	}, []string{"result"})
+
+	configGeneration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "config_generation",
+		Help: "The generation of the config new games are started with",
+	})
+)
+
+func init() {
+	prometheus.Register(configReloads)
+	prometheus.Register(configGeneration)
+}
+
+// generation is one version of the config, with the brains made from it.
+type generation struct {
+	n      int
+	config *Config
+	brains map[string]api.AI
+}
+
+func newGeneration(n int, c *Config) (*generation, error) {
+	gen := &generation{
+		n:      n,
+		config: c,
+		brains: map[string]api.AI{},
+	}
+
+	for _, sc := range c.Snakes {
+		ai, err := sc.make()
+		if err != nil {
+			return nil, err
+		}
+
+		gen.brains[sc.Name] = ai
+	}
+
+	return gen, nil
+}
+
+// mounts serves the snakes in the config. When the config is reloaded a new
+// generation of brains is swapped in for new games, while games already
+// being played keep the generation they started with until they end.
+type mounts struct {
+	path  string
+	token string
+	rec   *api.Recorder
+
+	// next serves everything that isn't a snake.
+	next http.Handler
+
+	current atomic.Value // *generation
+
+	// games holds the *generation every game being played is pinned to.
+	games api.StateStore
+
+	// lock is held while reloading.
+	lock sync.Mutex
+
+	// old are the generations swapped out that haven't been retired yet,
+	// guarded by oldLock.
+	old     []*generation
+	oldLock sync.Mutex
+
+	// servers has a *mount for every snake name ever mounted, so games
+	// against snakes that were taken out of the config can still finish.
+	servers sync.Map
+}
+
+// mount is one snake being served.
+type mount struct {
+	server  *api.Server
+	handler http.Handler
+}
+
+func newMounts(path, token string, c *Config, rec *api.Recorder) (*mounts, error) {
+	gen, err := newGeneration(1, c)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &mounts{
+		path:  path,
+		token: token,
+		rec:   rec,
+		next:  http.NotFoundHandler(),
+	}
+	m.games.Name = "generations"
+	m.swap(gen)
+
+	return m, nil
+}
+
+func (m *mounts) generation() *generation {
+	return m.current.Load().(*generation)
+}
+
+// swap makes gen the generation new games start with. The lock must be held
+// once m is in use.
+func (m *mounts) swap(gen *generation) {
+	for name := range gen.brains {
+		if _, ok := m.servers.Load(name); !ok {
+			srv, h := createSnake(name, pinned{name: name, m: m}, m.rec)
+			m.servers.Store(name, &mount{server: srv, handler: h})
+		}
+	}
+
+	if prev, ok := m.current.Load().(*generation); ok {
+		m.oldLock.Lock()
+		m.old = append(m.old, prev)
+		m.oldLock.Unlock()
+	}
+
+	m.current.Store(gen)
+	configGeneration.Set(float64(gen.n))
+	m.retire()
+}
+
+// retire retires the brains of old generations no game is being played with
+// any more, so what they hold on to is let go of.
+func (m *mounts) retire() {
+	m.oldLock.Lock()
+	defer m.oldLock.Unlock()
+
+	if len(m.old) == 0 {
+		return
+	}
+
+	// games that never ended count as long as they are kept
+	m.games.Sweep()
+	playing := map[*generation]bool{}
+	m.games.Each(func(_ api.GameKey, v interface{}) bool {
+		playing[v.(*generation)] = true
+		return true
+	})
+
+	kept := m.old[:0]
+	for _, gen := range m.old {
+		if playing[gen] {
+			kept = append(kept, gen)
+			continue
+		}
+
+		for _, ai := range gen.brains {
+			api.Retire(ai)
+		}
+	}
+	m.old = kept
+}
+
+// reload loads the config again and swaps it in. If the config is bad, the
+// one already loaded is kept.
+func (m *mounts) reload(ctx context.Context, why string) (*generation, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	f := ln.F{"why": why, "config": m.path}
+
+	c, err := loadConfig(m.path)
+	if err == nil {
+		var gen *generation
+		gen, err = newGeneration(m.generation().n+1, c)
+		if err == nil {
+			m.swap(gen)
+			configReloads.With(prometheus.Labels{"result": "ok"}).Inc()
+			ln.Log(ctx, f, ln.F{"generation": gen.n, "snakes": strings.Join(c.names(), ",")}, ln.Info("config reloaded"))
+
+			return gen, nil
+		}
+	}
+
+	configReloads.With(prometheus.Labels{"result": "error"}).Inc()
+	ln.Error(ctx, err, f, ln.Info("config not reloaded, keeping the old one"))
+
+	return nil, err
+}
+
+// fileStamp is enough to tell when a file has changed.
+type fileStamp struct {
+	modTime int64
+	size    int64
+}
+
+func stamp(path string) fileStamp {
+	st, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+
+	return fileStamp{modTime: st.ModTime().UnixNano(), size: st.Size()}
+}
+
+// watch reloads the config on SIGHUP, and whenever the file changes if
+// every is more than zero.
+func (m *mounts) watch(ctx context.Context, every time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if every > 0 && m.path != "" {
+		t := time.NewTicker(every)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	last := stamp(m.path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			m.reload(ctx, "sighup")
+		case <-tick:
+			// a config being written shows up as a change too; if it isn't
+			// valid yet it gets picked up on the next tick
+			if st := stamp(m.path); st != last {
+				last = st
+				m.reload(ctx, "changed")
+			}
+
+			m.retire()
+		}
+	}
+}
+
+// authorized checks r carries the admin token as a bearer token, answering
+// it if not. Without an admin token, admin endpoints don't exist.
+func (m *mounts) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if m.token == "" {
+		http.NotFound(w, r)
+		return false
+	}
+
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(m.token)) != 1 {
+		http.Error(w, "wrong token", http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
+
+// adminOnly lets only requests with the admin token through to h.
+func (m *mounts) adminOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.authorized(w, r) {
+			h.ServeHTTP(w, r)
+		}
+	})
+}
+
+// admin reloads the config when asked to by someone with the admin token.
+func (m *mounts) admin(w http.ResponseWriter, r *http.Request) {
+	if !m.authorized(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST to reload the config", http.StatusMethodNotAllowed)
+		return
+	}
+
+	gen, err := m.reload(r.Context(), "admin")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"generation": gen.n,
+		"snakes":     gen.config.names(),
+	})
+}
+
+// Brain implements api.Brains with the current generation.
+func (m *mounts) Brain(name string) (api.AI, bool) {
+	ai, ok := m.generation().brains[name]
+	return ai, ok
+}
+
+// ServeHTTP sends requests for /<name>/ to that snake. Snakes that aren't in
+// the config any more only answer for games they are already playing, and
+// for their debug state.
+func (m *mounts) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		name = name[:i]
+	}
+
+	_, mounted := m.generation().brains[name]
+	mt, known := m.servers.Load(name)
+
+	kind := path.Base(r.URL.Path)
+	finishing := r.Method == http.MethodPost && kind != api.KindStart
+	if !known || !(mounted || finishing || kind == "debug") {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+
+	mt.(*mount).handler.ServeHTTP(w, r)
+}
+
+// pinned is a snake that plays every game with the generation of its brain
+// the game started with.
+type pinned struct {
+	name string
+	m    *mounts
+}
+
+func (p pinned) brain(gen *generation) (api.AI, error) {
+	ai, ok := gen.brains[p.name]
+	if !ok {
+		return nil, fmt.Errorf("snake %s isn't in config generation %d", p.name, gen.n)
+	}
+
+	return ai, nil
+}
+
+// pin finds the generation sr's game is played with. Games that started
+// before the server did are pinned to the current one.
+func (p pinned) pin(sr api.SnakeRequest) *generation {
+	return p.m.games.Update(api.KeyOf(sr), func(v interface{}, ok bool) interface{} {
+		if ok {
+			return v
+		}
+
+		return p.m.generation()
+	}).(*generation)
+}
+
+func (p pinned) Ping() (*api.PingResponse, error) {
+	ai, err := p.brain(p.m.generation())
+	if err != nil {
+		return nil, err
+	}
+
+	return ai.Ping()
+}
+
+func (p pinned) Start(ctx context.Context, sr api.SnakeRequest) error {
+	gen := p.m.generation()
+	ai, err := p.brain(gen)
+	if err != nil {
+		return err
+	}
+
+	p.m.games.Put(api.KeyOf(sr), gen)
+	return ai.Start(ln.WithF(ctx, ln.F{"generation": gen.n}), sr)
+}
+
+func (p pinned) Move(ctx context.Context, sr api.SnakeRequest) (*api.MoveResponse, error) {
+	gen := p.pin(sr)
+	ai, err := p.brain(gen)
+	if err != nil {
+		return nil, err
+	}
+
+	return ai.Move(ln.WithF(ctx, ln.F{"generation": gen.n}), sr)
+}
+
+func (p pinned) End(ctx context.Context, sr api.SnakeRequest) error {
+	gen := p.pin(sr)
+	defer p.m.retire()
+	defer p.m.games.Delete(api.KeyOf(sr))
+
+	ai, err := p.brain(gen)
+	if err != nil {
+		return err
+	}
+
+	return ai.End(ln.WithF(ctx, ln.F{"generation": gen.n}), sr)
+}
+
+// Debug shows the state of the snake's brain in every generation it is
+// playing games with, by generation.
+func (p pinned) Debug() interface{} {
+	gens := map[*generation]bool{p.m.generation(): true}
+	p.m.games.Each(func(_ api.GameKey, v interface{}) bool {
+		gens[v.(*generation)] = true
+		return true
+	})
+
+	result := map[string]interface{}{}
+	for gen := range gens {
+		ai, err := p.brain(gen)
+		if err != nil {
+			continue
+		}
+
+		if state, ok := api.DebugState(ai); ok {
+			result[strconv.Itoa(gen.n)] = state
+		}
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	return result
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/snakes"
+)
+
+func TestMountsReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bsnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "snakes.json")
+	write := func(config string) {
+		if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"snakes": [{"name": "p", "brain": "pyra", "settings": {"min_length": 3}}]}`)
+	c, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := newMounts(path, "hunter2", c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	p := pinned{name: "p", m: m}
+	minLength := func(sr api.SnakeRequest) int {
+		return p.pin(sr).brains["p"].(*snakes.Pyra).MinLength
+	}
+
+	request := func(game string) api.SnakeRequest {
+		me := api.Snake{ID: "me", Health: 100, Body: []api.Coord{{X: 1, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: 0}}}
+		return api.SnakeRequest{
+			Game:  api.Game{ID: game},
+			Board: api.Board{Width: 5, Height: 5, Snakes: []api.Snake{me}, Food: []api.Coord{{X: 3, Y: 3}}},
+			You:   me,
+		}
+	}
+
+	old := request("old")
+	if err := p.Start(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+
+	write(`{"snakes": [{"name": "p", "brain": "pyra", "settings": {"min_length": 30}}]}`)
+	reload := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		m.admin(w, r)
+		return w
+	}
+
+	if w := reload("hunter3"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("a wrong token should be turned away, got: %d", w.Code)
+	}
+
+	if w := reload("hunter2"); w.Code != http.StatusOK {
+		t.Fatalf("wanted the config reloaded, got: %d %s", w.Code, w.Body)
+	}
+
+	young := request("new")
+	if err := p.Start(ctx, young); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := minLength(old); got != 3 {
+		t.Errorf("a game in progress should keep its config, got min_length %d", got)
+	}
+
+	if got := minLength(young); got != 30 {
+		t.Errorf("a new game should get the new config, got min_length %d", got)
+	}
+
+	// a broken config keeps the old one, and taking a snake out of the config
+	// lets its games finish but not start
+	write(`{"snakes": [{"name": "p", "brain": "pyra", "settings": {"min_lenght": 1}}]}`)
+	if w := reload("hunter2"); w.Code != http.StatusUnprocessableEntity || m.generation().n != 2 {
+		t.Fatalf("a bad config should be refused, got: %d, generation %d", w.Code, m.generation().n)
+	}
+
+	write(`{"snakes": [{"name": "q", "brain": "garen"}]}`)
+	if w := reload("hunter2"); w.Code != http.StatusOK {
+		t.Fatalf("wanted the config reloaded, got: %d %s", w.Code, w.Body)
+	}
+
+	post := func(path string) int {
+		w := httptest.NewRecorder()
+		body, _ := json.Marshal(old)
+		m.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+		return w.Code
+	}
+
+	if code := post("/p/move"); code != http.StatusOK {
+		t.Errorf("a game in progress should still be played, got: %d", code)
+	}
+
+	if code := post("/p/start"); code != http.StatusNotFound {
+		t.Errorf("a snake taken out of the config shouldn't start games, got: %d", code)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	var state map[string]map[string]json.RawMessage
+	if w := get("/p/debug"); w.Code != http.StatusOK {
+		t.Errorf("wanted pyra's state, got: %d", w.Code)
+	} else if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := state["1"]["old/me"]; !ok {
+		t.Errorf("wanted the state of the old game under generation 1, got: %v", state)
+	}
+
+	if w := get("/q/debug"); w.Code != http.StatusNotFound {
+		t.Errorf("garen keeps no state, got: %d", w.Code)
+	}
+
+	sv := m.snakeVars()
+	if len(sv) != 2 || sv[0].Name != "q" || sv[1].Name != "p" || sv[1].Mounted || sv[1].Stats.LiveGames != 1 {
+		t.Errorf("wanted q mounted and p finishing a game, got: %+v", sv)
+	}
+
+	// once the last game played with a generation ends, its brains let go
+	// of their games
+	first := p.pin(old).brains["p"].(*snakes.Pyra)
+	if err := p.End(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.Start(ctx, request("late")); err != nil {
+		t.Fatal(err)
+	}
+
+	if state, _ := json.Marshal(first.Debug()); string(state) != "{}" || len(m.old) != 1 {
+		t.Errorf("wanted generation 1 retired and 2 still playing, got state %s and %d old generations", state, len(m.old))
+	}
+}
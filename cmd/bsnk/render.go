@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/render"
+)
+
+const renderUsage = `usage: bsnk render [flags] <recording or request.json>
+
+Draws a game recording as an animated GIF, or one turn of it (or a snake
+request saved as JSON) as text, SVG or PNG. The snake the recording belongs
+to is drawn in the color its brain says it is.
+
+`
+
+func renderCmd(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	format := fs.String("format", "", "text, svg, png or gif, defaults to gif for recordings and text for requests")
+	out := fs.String("o", "", "file to write to instead of standard out")
+	turn := fs.Int("turn", -1, "turn of the recording to draw when not drawing a gif, -1 for the last")
+	brain := fs.String("brain", "", "snake to ask for its color, defaults to the one in the recording")
+	delay := fs.Duration("delay", 150*time.Millisecond, "time between frames of a gif")
+	color := fs.Bool("color", false, "draw text with ANSI colors")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), renderUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("need exactly one recording or request")
+	}
+
+	var records []api.Record
+	path := fs.Arg(0)
+	if strings.HasSuffix(path, ".json") {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var sr api.SnakeRequest
+		if err := json.Unmarshal(data, &sr); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		records = append(records, api.Record{Kind: api.KindMove, Request: sr})
+		if *format == "" {
+			*format = "text"
+		}
+	} else {
+		var err error
+		records, err = api.OpenRecording(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		if len(records) == 0 {
+			return fmt.Errorf("%s: empty recording", path)
+		}
+	}
+
+	if *brain == "" {
+		*brain = records[0].Snake
+	}
+
+	you := records[0].Request.You.ID
+	opts := render.Options{
+		You:    you,
+		Color:  *color,
+		Axes:   true,
+		Legend: true,
+	}
+
+	if ai, err := lookupBrain(*brain); err == nil {
+		opts.Colors = render.PingColors(map[string]api.AI{you: ai})
+	}
+
+	boards := render.Boards(records)
+	b := boards[len(boards)-1]
+	if *turn >= 0 {
+		found := false
+		for _, rec := range records {
+			if rec.Request.Turn == *turn {
+				b, found = rec.Request.Board, true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%s: no turn %d", path, *turn)
+		}
+	}
+
+	var draw func(io.Writer) error
+	switch *format {
+	case "", "gif":
+		draw = func(w io.Writer) error { return render.GIF(w, boards, opts, *delay) }
+	case "text":
+		draw = func(w io.Writer) error {
+			_, err := io.WriteString(w, render.Text(b, opts))
+			return err
+		}
+	case "svg":
+		draw = func(w io.Writer) error { return render.SVG(w, b, opts) }
+	case "png":
+		draw = func(w io.Writer) error { return render.PNG(w, b, opts) }
+	default:
+		return fmt.Errorf("unknown format %q, wanted text, svg, png or gif", *format)
+	}
+
+	return writeOut(*out, draw)
+}
+
+// writeOut calls draw with the file at path, or standard out if path is
+// empty.
+func writeOut(path string, draw func(io.Writer) error) error {
+	if path == "" {
+		return draw(os.Stdout)
+	}
+
+	fout, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := draw(fout); err != nil {
+		fout.Close()
+		return err
+	}
+
+	return fout.Close()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/render"
+)
+
+const replayUsage = `usage: bsnk replay -brain <snake> [flags] <recording or directory>...
+
+Feeds every request in the given game recordings to a snake and reports
+every turn where it picks a different move than the recording, with the
+board at that turn. Directories are searched for recordings.
+
+`
+
+func replay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	brain := fs.String("brain", "", "snake to replay the games with")
+	asJSON := fs.Bool("json", false, "print the differences as JSON lines")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), replayUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *brain == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("need a brain and at least one recording")
+	}
+
+	paths, err := findRecordings(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	total, differ := 0, 0
+	for _, path := range paths {
+		records, err := api.OpenRecording(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		ai, err := lookupBrain(*brain)
+		if err != nil {
+			return err
+		}
+
+		diffs, err := api.Replay(ctx, ai, records)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		moves := 0
+		for _, rec := range records {
+			if rec.Kind == api.KindMove {
+				moves++
+			}
+		}
+		total += moves
+		differ += len(diffs)
+
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			for _, d := range diffs {
+				if err := enc.Encode(d); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		fmt.Printf("%s: %d of %d moves differ\n", path, len(diffs), moves)
+		for _, d := range diffs {
+			fmt.Printf("\nturn %d: recorded %s, %s picked %s", d.Record.Request.Turn, moveName(d.Recorded()), *brain, moveName(d.Move))
+			if d.Error != "" {
+				fmt.Printf(" (%s)", d.Error)
+			}
+			fmt.Printf("\n%s\n", render.Request(d.Record.Request, render.Options{Axes: true, Legend: true}))
+		}
+	}
+
+	if !*asJSON {
+		fmt.Printf("%d recordings, %d of %d moves differ\n", len(paths), differ, total)
+	}
+
+	return nil
+}
+
+func moveName(d api.Direction) string {
+	if !d.Valid() {
+		return "nothing"
+	}
+
+	return d.String()
+}
+
+// findRecordings expands directories into the recordings inside them.
+func findRecordings(args []string) ([]string, error) {
+	var result []string
+	for _, arg := range args {
+		err := filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !info.IsDir() && (path == arg || strings.HasSuffix(path, api.RecordingExt)) {
+				result = append(result, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/match"
+	"github.com/Xe/bsnk/rules"
+	"within.website/ln"
+)
+
+const tournamentUsage = `usage: bsnk tournament [flags] <lineup> [lineup...]
+
+Plays many seeded games between lineups of snakes and rates every snake.
+Each lineup is a comma-separated list of snakes, such as pyra,greedy,sunset.
+Games cycle through every combination of lineup, board size and ruleset.
+
+`
+
+func tournament(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("tournament", flag.ExitOnError)
+	games := fs.Int("games", 100, "number of games to play")
+	sizes := fs.String("sizes", "11x11", "comma-separated board sizes to play on")
+	rulesets := fs.String("rulesets", "standard", "comma-separated rulesets to play with")
+	seed := fs.Int64("seed", 1, "seed of the first game, game i uses seed+i")
+	timeout := fs.Duration("timeout", api.DefaultTimeout, "time each snake has to pick a move")
+	maxTurns := fs.Int("max-turns", 1000, "end games as a draw after this many turns")
+	workers := fs.Int("workers", 0, "games to play at once, defaults to one per CPU")
+	asJSON := fs.Bool("json", false, "print the full report as JSON")
+	verbose := fs.Bool("v", false, "print every game as it finishes")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), tournamentUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no lineups given")
+	}
+
+	t := match.Tournament{
+		Games:    *games,
+		Timeout:  *timeout,
+		MaxTurns: *maxTurns,
+		Seed:     *seed,
+		Workers:  *workers,
+		NewAI:    lookupBrain,
+	}
+
+	for _, lineup := range fs.Args() {
+		names := strings.Split(lineup, ",")
+		for _, name := range names {
+			if _, err := lookupBrain(name); err != nil {
+				return err
+			}
+		}
+
+		t.Lineups = append(t.Lineups, names)
+	}
+
+	for _, size := range strings.Split(*sizes, ",") {
+		var s match.Size
+		if _, err := fmt.Sscanf(size, "%dx%d", &s.Width, &s.Height); err != nil {
+			return fmt.Errorf("bad board size %q: %w", size, err)
+		}
+
+		t.Sizes = append(t.Sizes, s)
+	}
+
+	for _, name := range strings.Split(*rulesets, ",") {
+		if _, err := rules.New(api.Ruleset{Name: name}, 0); err != nil {
+			return err
+		}
+
+		t.Rulesets = append(t.Rulesets, api.Ruleset{
+			Name:     name,
+			Settings: rules.DefaultSettings(),
+		})
+	}
+
+	if *verbose {
+		t.OnGame = func(g match.TournamentGame) {
+			fmt.Fprintf(os.Stderr, "game %d (%s %s, seed %d): winner %q after %d turns\n",
+				g.Index, g.Ruleset, g.Size, g.Seed, g.Winner, g.Turns)
+		}
+	}
+
+	start := time.Now()
+	report, err := t.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	ln.Log(ctx, ln.F{
+		"tournament_games":    len(report.Games),
+		"tournament_duration": time.Since(start).String(),
+	})
+
+	if *asJSON {
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		return e.Encode(report)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "snake\telo\t%d%% interval\tgames\twins\tdraws\tmean place\n", int(match.Confidence*100))
+	for _, r := range report.Ratings {
+		fmt.Fprintf(tw, "%s\t%.0f\t%.0f..%.0f\t%d\t%d\t%d\t%.2f\n",
+			r.Name, r.Elo, r.Low, r.High, r.Games, r.Wins, r.Draws, r.MeanPlace)
+	}
+	tw.Flush()
+
+	fmt.Printf("\n%d games, %.1f turns on average, seeds %d..%d\n",
+		len(report.Games), report.MeanTurns, *seed, *seed+int64(*games)-1)
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/snakes"
+)
+
+// started is when this process started.
+var started = time.Now()
+
+// snakeVars is what /vars says about a snake.
+type snakeVars struct {
+	Name string `json:"name"`
+
+	// Mounted is false for snakes that were taken out of the config but are
+	// still finishing games.
+	Mounted bool `json:"mounted"`
+
+	Brain string `json:"brain,omitempty"`
+	Color string `json:"color,omitempty"`
+	Head  string `json:"head,omitempty"`
+	Tail  string `json:"tail,omitempty"`
+
+	// Settings are the brain's settings with the config's decoded over the
+	// defaults.
+	Settings json.RawMessage `json:"settings,omitempty"`
+
+	Stats api.ServerStats `json:"stats"`
+}
+
+// snakeVars reports on every snake being served.
+func (m *mounts) snakeVars() []snakeVars {
+	gen := m.generation()
+	stats := func(name string) api.ServerStats {
+		mt, ok := m.servers.Load(name)
+		if !ok {
+			return api.ServerStats{}
+		}
+
+		return mt.(*mount).server.Stats()
+	}
+
+	var result []snakeVars
+	for _, sc := range gen.config.Snakes {
+		sv := snakeVars{
+			Name:    sc.Name,
+			Mounted: true,
+			Brain:   sc.brain(),
+			Color:   sc.Color,
+			Head:    sc.Head,
+			Tail:    sc.Tail,
+			Stats:   stats(sc.Name),
+		}
+
+		if b, ok := snakes.Lookup(sc.brain()); ok {
+			sv.Settings, _ = b.Settings(sc.Settings)
+		}
+
+		result = append(result, sv)
+	}
+
+	var retired []snakeVars
+	m.servers.Range(func(k, v interface{}) bool {
+		name := k.(string)
+		if _, ok := gen.brains[name]; ok {
+			return true
+		}
+
+		if st := v.(*mount).server.Stats(); st.LiveGames > 0 {
+			retired = append(retired, snakeVars{Name: name, Stats: st})
+		}
+
+		return true
+	})
+	sort.Slice(retired, func(i, j int) bool { return retired[i].Name < retired[j].Name })
+
+	return append(result, retired...)
+}
+
+// buildInfo is what went into this binary.
+func buildInfo() map[string]interface{} {
+	info := map[string]interface{}{
+		"go_version": runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info["path"] = bi.Main.Path
+		info["version"] = bi.Main.Version
+
+		deps := map[string]string{}
+		for _, dep := range bi.Deps {
+			deps[dep.Path] = dep.Version
+		}
+		info["deps"] = deps
+	}
+
+	return info
+}
+
+func vars(m *mounts) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gen := m.generation()
+		defaults := map[string]json.RawMessage{}
+		for _, name := range snakes.Names() {
+			b, _ := snakes.Lookup(name)
+			defaults[name] = b.Defaults()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		e.Encode(map[string]interface{}{
+			"git_rev":    *gitRev,
+			"build":      buildInfo(),
+			"started":    started,
+			"uptime":     api.Duration(time.Since(started).Truncate(time.Second)),
+			"generation": gen.n,
+			"config":     gen.config,
+			"brains":     defaults,
+			"snakes":     m.snakeVars(),
+		})
+	}
+}
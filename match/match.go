@@ -0,0 +1,350 @@
+// Package match plays whole games between api.AI brains in-process, using
+// package rules as the game engine.
+package match
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/rules"
+	"within.website/ln"
+)
+
+// Player is a snake taking part in a match.
+type Player struct {
+	Name string
+	AI   api.AI
+}
+
+// Match is one game between a set of players.
+type Match struct {
+	ID      string
+	Ruleset api.Ruleset
+	Width   int
+	Height  int
+
+	// Timeout is how long each snake has to pick a move. If zero,
+	// api.DefaultTimeout is used.
+	Timeout time.Duration
+
+	// Seed drives the starting positions and everything random the ruleset
+	// does, so the same seed and players replay the same game as long as
+	// the brains themselves are deterministic.
+	Seed int64
+
+	// MaxTurns ends the game as a draw between the survivors after this many
+	// turns. Zero means no limit.
+	MaxTurns int
+
+	Players []Player
+
+	// OnTurn, if set, is called with every turn as soon as it is played.
+	OnTurn func(Turn)
+}
+
+// Turn is the board at the start of a turn and what happened on it.
+type Turn struct {
+	Turn  int       `json:"turn"`
+	Board api.Board `json:"board"`
+
+	// Moves are the moves each snake made on this turn, by snake ID. They
+	// are empty on the final board of a game.
+	Moves map[string]api.Direction `json:"moves,omitempty"`
+
+	// Errors are the reasons snakes failed to pick a move, by snake ID.
+	Errors map[string]string `json:"errors,omitempty"`
+
+	Eliminations []rules.Elimination `json:"eliminations,omitempty"`
+}
+
+// Placement is how well one player did.
+type Placement struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// Place is 1 for the winner. Snakes eliminated on the same turn share a
+	// place.
+	Place int `json:"place"`
+
+	// Turn is the turn the snake was eliminated on, or the last turn of the
+	// game if it survived.
+	Turn   int    `json:"turn"`
+	Length int    `json:"length"`
+	Cause  string `json:"cause,omitempty"`
+}
+
+func (p Placement) survived() bool {
+	return p.Cause == ""
+}
+
+// Result is the outcome of a match.
+type Result struct {
+	ID    string `json:"id"`
+	Seed  int64  `json:"seed"`
+	Turns int    `json:"turns"`
+
+	// Winner is the name of the winning player, or empty for a draw.
+	Winner     string      `json:"winner"`
+	WinnerID   string      `json:"winner_id,omitempty"`
+	Placements []Placement `json:"placements"`
+}
+
+// F ln.F's the result.
+func (r Result) F() ln.F {
+	return ln.F{
+		"match_id":     r.ID,
+		"match_seed":   r.Seed,
+		"match_turns":  r.Turns,
+		"match_winner": r.Winner,
+	}
+}
+
+// SnakeID is the ID the snake for the i'th player gets.
+func SnakeID(i int) string {
+	return fmt.Sprintf("snake-%d", i)
+}
+
+// Run plays the match to the end.
+func (m Match) Run(ctx context.Context) (Result, error) {
+	if len(m.Players) == 0 {
+		return Result{}, errors.New("match: no players")
+	}
+
+	if m.ID == "" {
+		m.ID = fmt.Sprintf("local-%d", m.Seed)
+	}
+
+	if m.Timeout == 0 {
+		m.Timeout = api.DefaultTimeout
+	}
+
+	ruleset, err := rules.New(m.Ruleset, m.Seed)
+	if err != nil {
+		return Result{}, err
+	}
+
+	ids := make([]string, len(m.Players))
+	names := map[string]string{}
+	for i, pl := range m.Players {
+		ids[i] = SnakeID(i)
+		names[ids[i]] = pl.Name
+	}
+
+	board, err := rules.CreateInitialBoard(m.Width, m.Height, ids, rand.New(rand.NewSource(m.Seed)))
+	if err != nil {
+		return Result{}, err
+	}
+	board = rules.InitializeBoard(ruleset, board)
+
+	for i := range board.Snakes {
+		board.Snakes[i].Name = names[board.Snakes[i].ID]
+	}
+
+	game := api.Game{
+		ID:      m.ID,
+		Ruleset: m.Ruleset,
+		Map:     "standard",
+		Source:  "local",
+		Timeout: int(m.Timeout / time.Millisecond),
+	}
+
+	// last keeps every snake as it was the last time it was alive, so
+	// eliminated snakes can be told how the game ended.
+	last := map[string]api.Snake{}
+	for _, sn := range board.Snakes {
+		last[sn.ID] = sn
+	}
+
+	m.each(board.Snakes, func(i int, sn api.Snake) {
+		err := api.Guard(func() error {
+			return m.Players[i].AI.Start(ctx, request(game, 0, board, sn))
+		})
+		if err != nil {
+			ln.Error(ctx, err, ln.F{"match_id": m.ID, "snake_id": sn.ID})
+		}
+	})
+
+	var placed []Placement
+	turn := 0
+	for !ruleset.IsGameOver(board) && (m.MaxTurns == 0 || turn < m.MaxTurns) {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+
+		t := m.moves(ctx, game, turn, board)
+
+		next, elims, err := ruleset.Next(turn+1, board, t.moves())
+		if err != nil {
+			return Result{}, err
+		}
+		t.Eliminations = elims
+
+		if m.OnTurn != nil {
+			m.OnTurn(t)
+		}
+
+		turn++
+		board = next
+		for _, sn := range board.Snakes {
+			last[sn.ID] = sn
+		}
+
+		for _, e := range elims {
+			placed = append(placed, Placement{
+				ID:     e.ID,
+				Name:   names[e.ID],
+				Turn:   turn,
+				Length: len(last[e.ID].Body),
+				Cause:  e.Cause,
+			})
+		}
+	}
+
+	if m.OnTurn != nil {
+		m.OnTurn(Turn{Turn: turn, Board: board})
+	}
+
+	everyone := make([]api.Snake, len(ids))
+	for i, id := range ids {
+		everyone[i] = last[id]
+	}
+
+	m.each(everyone, func(i int, sn api.Snake) {
+		err := api.Guard(func() error {
+			return m.Players[i].AI.End(ctx, request(game, turn, board, sn))
+		})
+		if err != nil {
+			ln.Error(ctx, err, ln.F{"match_id": m.ID, "snake_id": sn.ID})
+		}
+	})
+
+	return m.result(turn, board, placed, names), nil
+}
+
+// each calls fn concurrently for the player behind every snake.
+func (m Match) each(snakes []api.Snake, fn func(i int, sn api.Snake)) {
+	var wg sync.WaitGroup
+
+	for _, sn := range snakes {
+		for i := range m.Players {
+			if SnakeID(i) != sn.ID {
+				continue
+			}
+
+			wg.Add(1)
+			go func(i int, sn api.Snake) {
+				defer wg.Done()
+				fn(i, sn)
+			}(i, sn)
+		}
+	}
+
+	wg.Wait()
+}
+
+// moves asks every snake on the board for its move.
+func (m Match) moves(ctx context.Context, game api.Game, turn int, board api.Board) Turn {
+	t := Turn{
+		Turn:   turn,
+		Board:  board,
+		Moves:  map[string]api.Direction{},
+		Errors: map[string]string{},
+	}
+
+	var lock sync.Mutex
+	m.each(board.Snakes, func(i int, sn api.Snake) {
+		ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+
+		// a broken brain loses its move like it would against the real
+		// engine, rather than crashing or hanging the whole match
+		mr, err := api.CallMove(ctx, m.Players[i].AI, request(game, turn, board, sn))
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		if err != nil {
+			t.Errors[sn.ID] = err.Error()
+			return
+		}
+
+		t.Moves[sn.ID] = mr.Move
+	})
+
+	if len(t.Errors) == 0 {
+		t.Errors = nil
+	}
+
+	return t
+}
+
+func (t Turn) moves() []rules.SnakeMove {
+	var result []rules.SnakeMove
+	for _, sn := range t.Board.Snakes {
+		if dir, ok := t.Moves[sn.ID]; ok {
+			result = append(result, rules.SnakeMove{ID: sn.ID, Move: dir})
+		}
+	}
+
+	return result
+}
+
+// result ranks the players. Survivors share first place; everyone else is
+// ranked by how long they lasted.
+func (m Match) result(turn int, board api.Board, placed []Placement, names map[string]string) Result {
+	r := Result{
+		ID:    m.ID,
+		Seed:  m.Seed,
+		Turns: turn,
+	}
+
+	for _, sn := range board.Snakes {
+		r.Placements = append(r.Placements, Placement{
+			ID:     sn.ID,
+			Name:   names[sn.ID],
+			Turn:   turn,
+			Length: len(sn.Body),
+		})
+	}
+
+	for i := len(placed) - 1; i >= 0; i-- {
+		r.Placements = append(r.Placements, placed[i])
+	}
+
+	for i := range r.Placements {
+		pl, prev := &r.Placements[i], Placement{}
+		if i > 0 {
+			prev = r.Placements[i-1]
+		}
+
+		switch {
+		case i == 0:
+			pl.Place = 1
+		case pl.Turn == prev.Turn && pl.survived() == prev.survived():
+			pl.Place = prev.Place
+		default:
+			pl.Place = i + 1
+		}
+	}
+
+	if len(r.Placements) > 0 && (len(r.Placements) == 1 || r.Placements[1].Place != 1) {
+		r.WinnerID = r.Placements[0].ID
+		r.Winner = r.Placements[0].Name
+	}
+
+	return r
+}
+
+func request(game api.Game, turn int, board api.Board, you api.Snake) api.SnakeRequest {
+	return api.SnakeRequest{
+		Game:  game,
+		Turn:  turn,
+		Board: board,
+		You:   you,
+	}
+}
@@ -0,0 +1,149 @@
+package match
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/snakes"
+)
+
+func TestMatch(t *testing.T) {
+	play := func(seed int64) ([]Turn, Result) {
+		var turns []Turn
+		m := Match{
+			Ruleset: api.Ruleset{Name: "standard"},
+			Width:   11,
+			Height:  11,
+			Seed:    seed,
+			Players: []Player{
+				{Name: "garen", AI: snakes.Garen{}},
+				{Name: "greedy", AI: &snakes.Greedy{}},
+			},
+			OnTurn: func(t Turn) {
+				turns = append(turns, t)
+			},
+		}
+
+		result, err := m.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return turns, result
+	}
+
+	turns, result := play(1)
+
+	if len(turns) != result.Turns+1 {
+		t.Errorf("wanted %d turns, got: %d", result.Turns+1, len(turns))
+	}
+
+	if len(result.Placements) != 2 {
+		t.Fatalf("wanted both players placed, got: %#v", result.Placements)
+	}
+
+	if result.Placements[0].Place != 1 || result.Placements[1].Place == 1 && result.Winner != "" {
+		t.Errorf("bad placements: %#v", result)
+	}
+
+	for _, turn := range turns[:len(turns)-1] {
+		if len(turn.Moves)+len(turn.Errors) != len(turn.Board.Snakes) {
+			t.Errorf("turn %d: every snake should have moved: %v %v", turn.Turn, turn.Moves, turn.Errors)
+		}
+	}
+
+	again, resultAgain := play(1)
+	if !reflect.DeepEqual(turns, again) || !reflect.DeepEqual(result, resultAgain) {
+		t.Error("the same seed should play the same game")
+	}
+}
+
+func TestMatchMaxTurns(t *testing.T) {
+	m := Match{
+		Width:    7,
+		Height:   7,
+		Seed:     3,
+		MaxTurns: 2,
+		Players: []Player{
+			{Name: "a", AI: snakes.Garen{}},
+			{Name: "b", AI: snakes.Garen{}},
+		},
+	}
+
+	result, err := m.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Turns != 2 || result.Winner != "" {
+		t.Errorf("wanted a draw after 2 turns, got: %#v", result)
+	}
+
+	for _, pl := range result.Placements {
+		if pl.Place != 1 {
+			t.Errorf("survivors should share first place: %#v", pl)
+		}
+	}
+}
+
+func TestTournament(t *testing.T) {
+	tr := Tournament{
+		Games:    12,
+		Lineups:  [][]string{{"garen", "greedy"}, {"greedy", "greedy"}},
+		Sizes:    []Size{{Width: 7, Height: 7}, {Width: 11, Height: 11}},
+		Rulesets: []api.Ruleset{{Name: "standard"}, {Name: "wrapped"}},
+		MaxTurns: 200,
+		Seed:     10,
+		Workers:  4,
+		NewAI: func(name string) (api.AI, error) {
+			switch name {
+			case "garen":
+				return snakes.Garen{}, nil
+			case "greedy":
+				return &snakes.Greedy{}, nil
+			}
+
+			t.Fatalf("unknown snake %s", name)
+			return nil, nil
+		},
+	}
+
+	report, err := tr.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Games) != tr.Games {
+		t.Fatalf("wanted %d games, got: %d", tr.Games, len(report.Games))
+	}
+
+	combos := map[string]int{}
+	for i, g := range report.Games {
+		if g.Index != i || g.Seed != tr.Seed+int64(i) {
+			t.Errorf("game %d is out of place: %#v", i, g)
+		}
+
+		combos[g.Ruleset+" "+g.Size.String()]++
+	}
+
+	if len(combos) != 4 {
+		t.Errorf("every size and ruleset should be played: %v", combos)
+	}
+
+	names := map[string]bool{}
+	for _, r := range report.Ratings {
+		names[r.Name] = true
+	}
+
+	for _, name := range []string{"garen", "greedy", "greedy#2"} {
+		if !names[name] {
+			t.Errorf("%s should be rated: %v", name, report.Ratings)
+		}
+	}
+
+	if report.MeanTurns <= 0 {
+		t.Errorf("games should take some turns: %v", report.MeanTurns)
+	}
+}
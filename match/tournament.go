@@ -0,0 +1,219 @@
+package match
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/rating"
+)
+
+// Size is the size of a board.
+type Size struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+func (s Size) String() string {
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+// Tournament plays many seeded matches in parallel and rates the players.
+//
+// Game i is played with seed Seed+i. The games cycle through every
+// combination of lineup, board size and ruleset, so each combination is
+// played about as often as every other.
+type Tournament struct {
+	Games    int
+	Lineups  [][]string
+	Sizes    []Size
+	Rulesets []api.Ruleset
+	Timeout  time.Duration
+	MaxTurns int
+	Seed     int64
+
+	// Workers is how many games are played at once. If zero, one per CPU.
+	Workers int
+
+	// NewAI makes a fresh brain by name for every player in every game.
+	NewAI func(name string) (api.AI, error)
+
+	// OnGame, if set, is called as each game finishes. It may be called
+	// from multiple goroutines at once.
+	OnGame func(TournamentGame)
+}
+
+// TournamentGame is the result of one game in a tournament.
+type TournamentGame struct {
+	Index   int    `json:"index"`
+	Ruleset string `json:"ruleset"`
+	Size    Size   `json:"size"`
+	Result
+}
+
+// Report is the outcome of a tournament.
+type Report struct {
+	Games   []TournamentGame `json:"games"`
+	Ratings []rating.Rating  `json:"ratings"`
+
+	// Places counts how often each player finished in each place, by name.
+	// Places[name][0] is the number of first places.
+	Places map[string][]int `json:"places"`
+
+	MeanTurns float64 `json:"mean_turns"`
+}
+
+// Confidence is the width of the confidence interval tournaments report.
+const Confidence = 0.95
+
+// Run plays every game in the tournament.
+func (t Tournament) Run(ctx context.Context) (Report, error) {
+	if len(t.Lineups) == 0 || len(t.Sizes) == 0 || len(t.Rulesets) == 0 {
+		return Report{}, fmt.Errorf("match: tournament needs lineups, sizes and rulesets")
+	}
+
+	workers := t.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	games := make([]TournamentGame, t.Games)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				g, err := t.play(ctx, i)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+
+				games[i] = g
+				if t.OnGame != nil {
+					t.OnGame(g)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < t.Games && ctx.Err() == nil; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return Report{}, firstErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Report{}, err
+	}
+
+	return t.report(games), nil
+}
+
+// play sets up and plays game i.
+func (t Tournament) play(ctx context.Context, i int) (TournamentGame, error) {
+	lineup := t.Lineups[i%len(t.Lineups)]
+	rest := i / len(t.Lineups)
+	size := t.Sizes[rest%len(t.Sizes)]
+	ruleset := t.Rulesets[(rest/len(t.Sizes))%len(t.Rulesets)]
+
+	m := Match{
+		ID:       fmt.Sprintf("tournament-%d-%d", t.Seed, i),
+		Ruleset:  ruleset,
+		Width:    size.Width,
+		Height:   size.Height,
+		Timeout:  t.Timeout,
+		Seed:     t.Seed + int64(i),
+		MaxTurns: t.MaxTurns,
+	}
+
+	for j, name := range PlayerNames(lineup) {
+		ai, err := t.NewAI(lineup[j])
+		if err != nil {
+			return TournamentGame{}, err
+		}
+
+		m.Players = append(m.Players, Player{Name: name, AI: ai})
+	}
+
+	result, err := m.Run(ctx)
+	if err != nil {
+		return TournamentGame{}, fmt.Errorf("match: game %d: %w", i, err)
+	}
+
+	return TournamentGame{
+		Index:   i,
+		Ruleset: ruleset.Name,
+		Size:    size,
+		Result:  result,
+	}, nil
+}
+
+// PlayerNames names the players in a lineup. When the same snake plays more
+// than once, the copies are told apart as name#2, name#3 and so on.
+func PlayerNames(lineup []string) []string {
+	seen := map[string]int{}
+	result := make([]string, len(lineup))
+	for i, name := range lineup {
+		seen[name]++
+		result[i] = name
+		if n := seen[name]; n > 1 {
+			result[i] = fmt.Sprintf("%s#%d", name, n)
+		}
+	}
+
+	return result
+}
+
+func (t Tournament) report(games []TournamentGame) Report {
+	r := Report{
+		Games:  games,
+		Places: map[string][]int{},
+	}
+
+	var rated []rating.Game
+	for _, g := range games {
+		rg := rating.Game{}
+		for _, pl := range g.Placements {
+			rg[pl.Name] = pl.Place
+
+			places := r.Places[pl.Name]
+			for len(places) < len(g.Placements) {
+				places = append(places, 0)
+			}
+			places[pl.Place-1]++
+			r.Places[pl.Name] = places
+		}
+
+		rated = append(rated, rg)
+		r.MeanTurns += float64(g.Turns)
+	}
+
+	if len(games) > 0 {
+		r.MeanTurns /= float64(len(games))
+	}
+
+	r.Ratings = rating.Compute(rated, 1000, Confidence, t.Seed)
+
+	return r
+}
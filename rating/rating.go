@@ -0,0 +1,210 @@
+// Package rating turns game placements into Elo-scale ratings with
+// confidence intervals.
+//
+// Ratings are fit with the Bradley-Terry model, which is what Elo
+// approximates one game at a time. Fitting every game at once means the
+// order games finished in doesn't matter, so parallel tournaments give
+// stable numbers. Multiplayer games are split into every pair of players in
+// them: the better placed player wins the pair and equal places are a draw.
+package rating
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Base is the rating of an average player.
+const Base = 1500
+
+// Game is the outcome of one game: the place each player finished in, by
+// name. Lower places are better.
+type Game map[string]int
+
+// Rating is how strong a player is.
+type Rating struct {
+	Name string  `json:"name"`
+	Elo  float64 `json:"elo"`
+
+	// Low and High are the bounds of the confidence interval for Elo.
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+
+	Games int `json:"games"`
+	Wins  int `json:"wins"`
+	Draws int `json:"draws"`
+
+	// MeanPlace is the average place the player finished in.
+	MeanPlace float64 `json:"mean_place"`
+}
+
+// Fit computes the Elo rating of every player in games.
+func Fit(games []Game) map[string]float64 {
+	index := map[string]int{}
+	var names []string
+	for _, g := range games {
+		for name := range g {
+			if _, ok := index[name]; !ok {
+				index[name] = -1
+				names = append(names, name)
+			}
+		}
+	}
+
+	// iterate in a fixed order so the floating point sums, and with them
+	// the ratings, come out exactly the same every time
+	sort.Strings(names)
+	for i, name := range names {
+		index[name] = i
+	}
+
+	n := len(names)
+	wins := make([]float64, n)
+	played := make([][]float64, n)
+	for i := range played {
+		played[i] = make([]float64, n)
+	}
+
+	for _, g := range games {
+		for a, pa := range g {
+			for b, pb := range g {
+				if a == b {
+					continue
+				}
+
+				i, j := index[a], index[b]
+				played[i][j]++
+				switch {
+				case pa < pb:
+					wins[i]++
+				case pa == pb:
+					wins[i] += 0.5
+				}
+			}
+		}
+	}
+
+	// every player gets a virtual win and loss against an average player
+	// so that players who never win or never lose still get a finite rating
+	const prior = 1.0
+
+	strength := make([]float64, n)
+	for i := range strength {
+		strength[i] = 1
+	}
+
+	next := make([]float64, n)
+	for iter := 0; iter < 1000; iter++ {
+		delta := 0.0
+
+		for i, s := range strength {
+			denom := prior * 2 / (s + 1)
+			for j, o := range strength {
+				if played[i][j] > 0 {
+					denom += played[i][j] / (s + o)
+				}
+			}
+
+			next[i] = (wins[i] + prior) / denom
+			delta = math.Max(delta, math.Abs(next[i]-s))
+		}
+
+		strength, next = next, strength
+		if delta < 1e-9 {
+			break
+		}
+	}
+
+	result := make(map[string]float64, n)
+	for i, name := range names {
+		result[name] = Base + 400*math.Log10(strength[i])
+	}
+
+	return result
+}
+
+// Compute rates every player in games. The confidence interval is found by
+// refitting the ratings on resamples copies of the games drawn with
+// replacement and taking the middle confidence (such as 0.95) of the
+// results. The seed makes the interval reproducible.
+func Compute(games []Game, resamples int, confidence float64, seed int64) []Rating {
+	elo := Fit(games)
+
+	ratings := map[string]*Rating{}
+	for name, e := range elo {
+		ratings[name] = &Rating{Name: name, Elo: e, Low: e, High: e}
+	}
+
+	for _, g := range games {
+		best, winners := math.MaxInt32, 0
+		for _, place := range g {
+			switch {
+			case place < best:
+				best, winners = place, 1
+			case place == best:
+				winners++
+			}
+		}
+
+		for name, place := range g {
+			r := ratings[name]
+			r.Games++
+			r.MeanPlace += float64(place)
+
+			switch {
+			case place == best && winners == 1:
+				r.Wins++
+			case place == best:
+				r.Draws++
+			}
+		}
+	}
+
+	if resamples > 0 && len(games) > 0 {
+		rng := rand.New(rand.NewSource(seed))
+		samples := map[string][]float64{}
+
+		sample := make([]Game, len(games))
+		for i := 0; i < resamples; i++ {
+			for j := range sample {
+				sample[j] = games[rng.Intn(len(games))]
+			}
+
+			for name, e := range Fit(sample) {
+				samples[name] = append(samples[name], e)
+			}
+		}
+
+		tail := (1 - confidence) / 2
+		for name, xs := range samples {
+			sort.Float64s(xs)
+			ratings[name].Low = percentile(xs, tail)
+			ratings[name].High = percentile(xs, 1-tail)
+		}
+	}
+
+	var result []Rating
+	for _, r := range ratings {
+		if r.Games > 0 {
+			r.MeanPlace /= float64(r.Games)
+		}
+
+		result = append(result, *r)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Elo == result[j].Elo {
+			return result[i].Name < result[j].Name
+		}
+
+		return result[i].Elo > result[j].Elo
+	})
+
+	return result
+}
+
+// percentile picks the p'th percentile out of sorted xs.
+func percentile(xs []float64, p float64) float64 {
+	i := int(math.Round(p * float64(len(xs)-1)))
+	return xs[i]
+}
@@ -0,0 +1,59 @@
+package rating
+
+import "testing"
+
+func TestCompute(t *testing.T) {
+	var games []Game
+	for i := 0; i < 30; i++ {
+		switch {
+		case i%3 == 0:
+			games = append(games, Game{"strong": 1, "weak": 2, "middle": 2})
+		case i%5 == 0:
+			games = append(games, Game{"middle": 1, "strong": 2, "weak": 3})
+		default:
+			games = append(games, Game{"strong": 1, "middle": 2, "weak": 3})
+		}
+	}
+
+	ratings := Compute(games, 200, 0.95, 1)
+	if len(ratings) != 3 {
+		t.Fatalf("wanted 3 ratings, got: %v", ratings)
+	}
+
+	for i, name := range []string{"strong", "middle", "weak"} {
+		r := ratings[i]
+		if r.Name != name {
+			t.Errorf("wanted %s in position %d, got: %v", name, i, ratings)
+		}
+
+		if r.Low > r.Elo || r.High < r.Elo || r.Low == r.High {
+			t.Errorf("%s: bad interval %.1f < %.1f < %.1f", name, r.Low, r.Elo, r.High)
+		}
+
+		if r.Games != 30 {
+			t.Errorf("%s: wanted 30 games, got: %d", name, r.Games)
+		}
+	}
+
+	if s := ratings[0]; s.Wins != 26 || s.MeanPlace <= 1 {
+		t.Errorf("strong: wanted 26 wins, got: %#v", s)
+	}
+
+	again := Compute(games, 200, 0.95, 1)
+	for i := range ratings {
+		if ratings[i] != again[i] {
+			t.Errorf("ratings should be reproducible: %v != %v", ratings[i], again[i])
+		}
+	}
+}
+
+func TestFitEven(t *testing.T) {
+	elo := Fit([]Game{{"a": 1, "b": 2}, {"a": 2, "b": 1}, {"a": 1, "b": 1}})
+	if d := elo["a"] - elo["b"]; d > 1e-6 || d < -1e-6 {
+		t.Errorf("evenly matched players should have the same rating: %v", elo)
+	}
+
+	if d := elo["a"] - Base; d > 1e-6 || d < -1e-6 {
+		t.Errorf("evenly matched players should be rated %d: %v", Base, elo)
+	}
+}
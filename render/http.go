@@ -0,0 +1,133 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+)
+
+// MaxBody is the most Handler reads of a request.
+const MaxBody = 16 << 20
+
+// Handler draws what is POSTed to it. The format query parameter picks
+// what comes back:
+//
+//	text (default)  a snake request drawn as text, with the color, axes
+//	                and legend query parameters turning on those Options
+//	svg, png        a snake request drawn as a picture
+//	gif             a game recording (as written by api.Recorder) drawn
+//	                as an animation, with delay between frames
+//
+// The snake that was sent a request is drawn in the color its brain in
+// brains says it is. The brain is named by the snake query parameter, or by
+// the recording. Bodies bigger than MaxBody and boards CheckSize or CheckGIF
+// turn down are bad requests.
+func Handler(brains api.Brains) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST a snake request or game recording to render it", http.StatusMethodNotAllowed)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, MaxBody)
+
+		q := r.URL.Query()
+		flag := func(name string) bool {
+			v, _ := strconv.ParseBool(q.Get(name))
+			return v
+		}
+
+		colors := func(snake, id string) map[string]string {
+			if brains == nil {
+				return nil
+			}
+
+			ai, ok := brains.Brain(snake)
+			if !ok {
+				return nil
+			}
+
+			return PingColors(map[string]api.AI{id: ai})
+		}
+
+		if q.Get("format") == "gif" {
+			records, err := api.ReadRecords(r.Body)
+			if err != nil || len(records) == 0 {
+				http.Error(w, "bad recording", http.StatusBadRequest)
+				return
+			}
+
+			delay, err := time.ParseDuration(q.Get("delay"))
+			if err != nil {
+				delay = 150 * time.Millisecond
+			}
+
+			boards := Boards(records)
+			if err := CheckGIF(boards); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			you := records[0].Request.You.ID
+			opts := Options{You: you, Colors: colors(records[0].Snake, you)}
+			serve(w, "image/gif", func(w io.Writer) error {
+				return GIF(w, boards, opts, delay)
+			})
+			return
+		}
+
+		var sr api.SnakeRequest
+		if err := json.NewDecoder(r.Body).Decode(&sr); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+
+		if err := CheckSize(sr.Board); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		opts := Options{
+			You:    sr.You.ID,
+			Colors: colors(q.Get("snake"), sr.You.ID),
+			Color:  flag("color"),
+			Axes:   flag("axes"),
+			Legend: flag("legend"),
+		}
+
+		switch q.Get("format") {
+		case "", "text":
+			serve(w, "text/plain; charset=utf-8", func(w io.Writer) error {
+				_, err := io.WriteString(w, Text(sr.Board, opts))
+				return err
+			})
+		case "svg":
+			serve(w, "image/svg+xml", func(w io.Writer) error {
+				return SVG(w, sr.Board, opts)
+			})
+		case "png":
+			serve(w, "image/png", func(w io.Writer) error {
+				return PNG(w, sr.Board, opts)
+			})
+		default:
+			http.Error(w, "unknown format, wanted text, svg, png or gif", http.StatusBadRequest)
+		}
+	})
+}
+
+// serve draws into a buffer first so errors can still be reported as such.
+func serve(w http.ResponseWriter, contentType string, draw func(io.Writer) error) {
+	var buf bytes.Buffer
+	if err := draw(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
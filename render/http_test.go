@@ -0,0 +1,31 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	body := `{"board": {"width": 2, "height": 1, "food": [{"x": 1, "y": 0}]}, "you": {"id": "me"}}`
+
+	w := httptest.NewRecorder()
+	Handler(nil).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/render", strings.NewReader(body)))
+
+	if w.Code != http.StatusOK || w.Body.String() != ".*\n" {
+		t.Errorf("wanted .*, got: %d %q", w.Code, w.Body)
+	}
+
+	for _, body := range []string{
+		`{"board": {"width": 100000, "height": 100000}}`,
+		`{"board": {"width": -1, "height": 5}}`,
+	} {
+		w := httptest.NewRecorder()
+		Handler(nil).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/render?format=png", strings.NewReader(body)))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s should be turned away, got: %d", body, w.Code)
+		}
+	}
+}
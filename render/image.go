@@ -0,0 +1,371 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+)
+
+// CellSize is how many pixels wide each square of the board is in images.
+const CellSize = 20
+
+// MaxBoardSize is the widest and tallest board that is drawn.
+const MaxBoardSize = 50
+
+// MaxGIFPixels is the most pixels, over every frame, a GIF is drawn with.
+// Frames are held in memory until the GIF is written, at a byte a pixel.
+const MaxGIFPixels = 1 << 27
+
+// CheckSize makes sure b is a board that can be drawn.
+func CheckSize(b api.Board) error {
+	if b.Width <= 0 || b.Height <= 0 || b.Width > MaxBoardSize || b.Height > MaxBoardSize {
+		return fmt.Errorf("render: can't draw a %dx%d board, wanted 1 to %d squares a side", b.Width, b.Height, MaxBoardSize)
+	}
+
+	return nil
+}
+
+// Colors of everything in images that isn't a snake.
+var (
+	background  = color.RGBA{0x1e, 0x1e, 0x24, 0xff}
+	square      = color.RGBA{0x2a, 0x2a, 0x32, 0xff}
+	foodColor   = color.RGBA{0xff, 0x5c, 0x5c, 0xff}
+	hazardColor = color.RGBA{0x5a, 0x1e, 0x1e, 0xff}
+	pathColor   = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// PingColors asks every brain what color it is, by snake ID, to use as
+// Options.Colors. Brains that fail to answer or don't pick a color are left
+// out.
+func PingColors(brains map[string]api.AI) map[string]string {
+	result := map[string]string{}
+	for id, ai := range brains {
+		pr, err := ai.Ping()
+		if err != nil || pr == nil || pr.Color == "" {
+			continue
+		}
+
+		result[id] = pr.Color
+	}
+
+	return result
+}
+
+// canvas is something boards can be drawn on.
+type canvas interface {
+	rect(r image.Rectangle, c color.Color)
+	circle(r image.Rectangle, radius int, c color.Color)
+
+	// group marks the shapes between it and the next call to end as one
+	// thing, with a title.
+	group(title string)
+	end()
+}
+
+// paint draws a board on a canvas.
+func paint(cv canvas, b api.Board, opts Options) {
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			cv.rect(cellRect(b, api.Coord{X: x, Y: y}).Inset(1), square)
+		}
+	}
+
+	for c, level := range heat(opts.Heatmap) {
+		cv.rect(cellRect(b, c).Inset(1), rgb(grey(level)))
+	}
+
+	for _, hz := range b.Hazards {
+		cv.rect(cellRect(b, hz).Inset(1), hazardColor)
+	}
+
+	for _, c := range opts.Path {
+		cv.rect(cellRect(b, c).Inset(CellSize*3/8), pathColor)
+	}
+
+	for _, fd := range b.Food {
+		cv.circle(cellRect(b, fd), CellSize/3, foodColor)
+	}
+
+	for i, sn := range b.Snakes {
+		c := rgb(snakeColor(sn, i, opts))
+		cv.group(fmt.Sprintf("%s health=%d length=%d", sn.Name, sn.Health, len(sn.Body)))
+
+		for j, part := range sn.Body {
+			if j == 0 {
+				cv.rect(cellRect(b, part).Inset(1), c)
+			} else {
+				cv.rect(cellRect(b, part).Inset(3), c)
+			}
+
+			// join each segment to the next so the snake reads as one piece
+			if j+1 < len(sn.Body) {
+				cv.rect(bridge(b, part, sn.Body[j+1]), c)
+			}
+		}
+
+		if len(sn.Body) > 0 && sn.ID == opts.You {
+			// an eye so you can tell which snake is you
+			cv.circle(cellRect(b, sn.Body[0]), CellSize/8, background)
+		}
+
+		cv.end()
+	}
+}
+
+// Image draws a board as a picture, CellSize pixels per square, with the top
+// row at the top.
+func Image(b api.Board, opts Options) (*image.RGBA, error) {
+	if err := CheckSize(b); err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, b.Width*CellSize, b.Height*CellSize))
+	drawImage(img, b, opts)
+
+	return img, nil
+}
+
+// drawImage draws b over all of img, which is the right size for it.
+func drawImage(img *image.RGBA, b api.Board, opts Options) {
+	cv := rasterCanvas{img}
+	cv.rect(img.Bounds(), background)
+	paint(cv, b, opts)
+}
+
+type rasterCanvas struct {
+	img *image.RGBA
+}
+
+func (cv rasterCanvas) rect(r image.Rectangle, c color.Color) {
+	draw.Draw(cv.img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+func (rasterCanvas) group(string) {}
+func (rasterCanvas) end()         {}
+
+func (cv rasterCanvas) circle(r image.Rectangle, radius int, c color.Color) {
+	mid := r.Min.Add(r.Max).Div(2)
+	for y := -radius; y <= radius; y++ {
+		for x := -radius; x <= radius; x++ {
+			if x*x+y*y <= radius*radius {
+				cv.img.Set(mid.X+x, mid.Y+y, c)
+			}
+		}
+	}
+}
+
+// CheckGIF makes sure boards can be drawn as a GIF.
+func CheckGIF(boards []api.Board) error {
+	pixels := 0
+	for _, b := range boards {
+		if err := CheckSize(b); err != nil {
+			return err
+		}
+		pixels += b.Width * CellSize * b.Height * CellSize
+	}
+
+	if pixels > MaxGIFPixels {
+		return fmt.Errorf("render: %d frames is too many to draw at this board size", len(boards))
+	}
+
+	return nil
+}
+
+// PNG draws a board as a PNG image.
+func PNG(w io.Writer, b api.Board, opts Options) error {
+	img, err := Image(b, opts)
+	if err != nil {
+		return err
+	}
+
+	return png.Encode(w, img)
+}
+
+// GIF draws every board as a frame of an animated GIF, delay apart. The last
+// frame is held for a second so the end of the game doesn't fly by. Boards
+// CheckGIF turns down aren't drawn.
+func GIF(w io.Writer, boards []api.Board, opts Options, delay time.Duration) error {
+	if len(boards) == 0 {
+		return fmt.Errorf("render: no boards to draw")
+	}
+
+	if err := CheckGIF(boards); err != nil {
+		return err
+	}
+
+	// each frame is drawn in full color and then given its own palette, so
+	// only one full color frame is ever held
+	opts.Colors = Colors(boards[0], opts)
+	anim := &gif.GIF{}
+	var frame *image.RGBA
+	for i, b := range boards {
+		bounds := image.Rect(0, 0, b.Width*CellSize, b.Height*CellSize)
+		if frame == nil || frame.Bounds() != bounds {
+			frame = image.NewRGBA(bounds)
+		}
+		drawImage(frame, b, opts)
+
+		pm := image.NewPaletted(bounds, framePalette(frame))
+		draw.Draw(pm, bounds, frame, image.Point{}, draw.Src)
+
+		d := int(delay / (10 * time.Millisecond))
+		if i == len(boards)-1 {
+			d += 100
+		}
+
+		anim.Image = append(anim.Image, pm)
+		anim.Delay = append(anim.Delay, d)
+	}
+
+	return gif.EncodeAll(w, anim)
+}
+
+// Boards picks the board of every turn out of a game recording, in order.
+func Boards(records []api.Record) []api.Board {
+	var result []api.Board
+	last := -1
+	for _, rec := range records {
+		b := rec.Request.Board
+		switch {
+		case len(result) > 0 && rec.Request.Turn == last:
+			result[len(result)-1] = b
+		default:
+			result = append(result, b)
+		}
+		last = rec.Request.Turn
+	}
+
+	return result
+}
+
+// framePalette is every color used in frame, which is almost always few
+// enough to fit in a GIF. If not, a general purpose palette is used.
+func framePalette(frame *image.RGBA) color.Palette {
+	seen := map[color.RGBA]bool{}
+	var result color.Palette
+	for i := 0; i < len(frame.Pix); i += 4 {
+		c := color.RGBA{frame.Pix[i], frame.Pix[i+1], frame.Pix[i+2], frame.Pix[i+3]}
+		if seen[c] {
+			continue
+		}
+
+		if len(result) == 256 {
+			return palette.Plan9
+		}
+
+		seen[c] = true
+		result = append(result, c)
+	}
+
+	return result
+}
+
+// SVG draws a board as an SVG image. Hovering over a snake shows its name,
+// health and length.
+func SVG(w io.Writer, b api.Board, opts Options) error {
+	if err := CheckSize(b); err != nil {
+		return err
+	}
+
+	cv := &svgCanvas{}
+	width, height := b.Width*CellSize, b.Height*CellSize
+	fmt.Fprintf(cv, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	cv.rect(image.Rect(0, 0, width, height), background)
+	paint(cv, b, opts)
+	cv.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, cv.String())
+	return err
+}
+
+type svgCanvas struct {
+	strings.Builder
+}
+
+func (cv *svgCanvas) rect(r image.Rectangle, c color.Color) {
+	if r.Empty() {
+		return
+	}
+
+	fmt.Fprintf(cv, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", r.Min.X, r.Min.Y, r.Dx(), r.Dy(), hex(c))
+}
+
+func (cv *svgCanvas) circle(r image.Rectangle, radius int, c color.Color) {
+	mid := r.Min.Add(r.Max).Div(2)
+	fmt.Fprintf(cv, `<circle cx="%d" cy="%d" r="%d" fill="%s"/>`+"\n", mid.X, mid.Y, radius, hex(c))
+}
+
+func (cv *svgCanvas) group(title string) {
+	fmt.Fprintf(cv, "<g><title>%s</title>\n", escape(title))
+}
+
+func (cv *svgCanvas) end() {
+	cv.WriteString("</g>\n")
+}
+
+// cellRect is the pixels a square of the board covers.
+func cellRect(b api.Board, c api.Coord) image.Rectangle {
+	x, y := c.X*CellSize, (b.Height-1-c.Y)*CellSize
+	return image.Rect(x, y, x+CellSize, y+CellSize)
+}
+
+// bridge covers the gap between two neighboring body segments, or nothing
+// if they aren't next to each other, such as when a snake wraps around the
+// edge of the board.
+func bridge(b api.Board, from, to api.Coord) image.Rectangle {
+	for _, n := range from.Neighbors() {
+		if n == to {
+			return cellRect(b, from).Inset(3).Union(cellRect(b, to).Inset(3))
+		}
+	}
+
+	return image.Rectangle{}
+}
+
+// heat scales a heatmap to levels 0 through 9.
+func heat(heatmap map[api.Coord]float64) map[api.Coord]int {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range heatmap {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+
+	result := make(map[api.Coord]int, len(heatmap))
+	for c, v := range heatmap {
+		level := 0
+		if hi > lo {
+			level = int(math.Round(9 * (v - lo) / (hi - lo)))
+		}
+		result[c] = level
+	}
+
+	return result
+}
+
+// rgb parses a hex color, falling back to grey.
+func rgb(s string) color.RGBA {
+	r, g, b, ok := parseHex(s)
+	if !ok {
+		return color.RGBA{0x88, 0x88, 0x88, 0xff}
+	}
+
+	return color.RGBA{r, g, b, 0xff}
+}
+
+func hex(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(s)
+}
@@ -0,0 +1,100 @@
+package render
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+)
+
+type colorBrain struct {
+	api.AI
+	color string
+}
+
+func (c colorBrain) Ping() (*api.PingResponse, error) {
+	return &api.PingResponse{Color: c.color}, nil
+}
+
+func TestImage(t *testing.T) {
+	b := testBoard()
+	opts := Options{
+		You:    "me",
+		Colors: PingColors(map[string]api.AI{"them": colorBrain{color: "#c79dd7"}}),
+	}
+
+	var buf bytes.Buffer
+	if err := PNG(&buf, b, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := img.Bounds().Size(); size.X != 6*CellSize || size.Y != 4*CellSize {
+		t.Fatalf("wrong size: %v", size)
+	}
+
+	// the head of "them" is at (4, 2), which is the second row from the top
+	got := color.RGBAModel.Convert(img.At(4*CellSize+2, 1*CellSize+2))
+	if want := (color.RGBA{0xc7, 0x9d, 0xd7, 0xff}); got != want {
+		t.Errorf("wanted the snake in its pinged color %v, got: %v", want, got)
+	}
+
+	buf.Reset()
+	if err := SVG(&buf, b, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), `fill="#c79dd7"`) || !strings.Contains(buf.String(), "<title>greedy health=50 length=4</title>") {
+		t.Errorf("svg is missing the snake: %s", buf.String())
+	}
+}
+
+func TestGIF(t *testing.T) {
+	b := testBoard()
+	var records []api.Record
+	for turn := 0; turn < 3; turn++ {
+		if turn == 0 {
+			records = append(records, api.Record{Kind: api.KindStart, Request: api.SnakeRequest{Turn: turn, Board: b}})
+		}
+
+		records = append(records, api.Record{Kind: api.KindMove, Request: api.SnakeRequest{Turn: turn, Board: b}})
+		b.Snakes[0].Body = append([]api.Coord{b.Snakes[0].Body[0].Up()}, b.Snakes[0].Body[:2]...)
+	}
+
+	boards := Boards(records)
+	if len(boards) != 3 {
+		t.Fatalf("wanted a board per turn, got %d", len(boards))
+	}
+
+	var buf bytes.Buffer
+	if err := GIF(&buf, boards, Options{}, 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(anim.Image) != 3 || anim.Delay[0] != 10 || anim.Delay[2] != 110 {
+		t.Errorf("wrong frames: %d frames, delays %v", len(anim.Image), anim.Delay)
+	}
+
+	long := make([]api.Board, MaxGIFPixels/(b.Width*b.Height*CellSize*CellSize)+1)
+	for i := range long {
+		long[i] = b
+	}
+	if err := GIF(ioutil.Discard, long, Options{}, 100*time.Millisecond); err == nil {
+		t.Error("a game too long to hold in memory should be turned down")
+	}
+}
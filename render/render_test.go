@@ -0,0 +1,28 @@
+package render
+
+import (
+	"github.com/Xe/bsnk/api"
+)
+
+func testBoard() api.Board {
+	return api.Board{
+		Width:   6,
+		Height:  4,
+		Food:    []api.Coord{{X: 5, Y: 3}},
+		Hazards: []api.Coord{{X: 0, Y: 3}, {X: 0, Y: 2}},
+		Snakes: []api.Snake{
+			{
+				ID:     "me",
+				Name:   "pyra",
+				Health: 90,
+				Body:   []api.Coord{{X: 1, Y: 1}, {X: 1, Y: 0}, {X: 2, Y: 0}},
+			},
+			{
+				ID:     "them",
+				Name:   "greedy",
+				Health: 50,
+				Body:   []api.Coord{{X: 4, Y: 2}, {X: 4, Y: 1}, {X: 5, Y: 1}, {X: 5, Y: 0}},
+			},
+		},
+	}
+}
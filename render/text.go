@@ -0,0 +1,259 @@
+// Package render draws boards so humans can look at them.
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Xe/bsnk/api"
+)
+
+// Glyphs used by Text. Snakes are drawn with letters: A, B, C and so on for
+// their heads and a, b, c for their bodies, in the order they are on the
+// board. The snake whose turn it is is always Y.
+const (
+	Empty  = '.'
+	Food   = '*'
+	Hazard = '~'
+	Tail   = '+'
+	Path   = 'o'
+	You    = 'Y'
+)
+
+// Options control how a board is drawn.
+type Options struct {
+	// You is the ID of the snake whose turn it is.
+	You string
+
+	// Color draws with ANSI colors. Each snake is drawn in the color from
+	// Colors, or its customization color, or one from a built-in palette.
+	Color  bool
+	Colors map[string]string
+
+	// Axes labels the rows and columns with their coordinates.
+	Axes bool
+
+	// Legend lists every snake under the board.
+	Legend bool
+
+	// Path is drawn over empty cells, such as the path a brain plans to
+	// take.
+	Path []api.Coord
+
+	// Heatmap is drawn over empty cells as digits from 0 (the lowest
+	// score) to 9 (the highest), or as shades of grey with Color.
+	Heatmap map[api.Coord]float64
+}
+
+// palette is used for snakes that don't say what color they are.
+var snakePalette = []string{"#e6194b", "#3cb44b", "#4363d8", "#f58231", "#911eb4", "#46f0f0", "#f032e6", "#bcf60c"}
+
+// cell is what gets drawn in one square of the board.
+type cell struct {
+	glyph byte
+	fg    string
+	bg    string
+	bold  bool
+}
+
+// Text draws a board as text, top row first.
+func Text(b api.Board, opts Options) string {
+	grid := make([]cell, b.Width*b.Height)
+	index := b.NewGrid()
+	set := func(c api.Coord, cl cell) {
+		if index.Inside(c) {
+			grid[index.Index(c)] = cl
+		}
+	}
+
+	for i := range grid {
+		grid[i] = cell{glyph: Empty}
+	}
+
+	for c, level := range heat(opts.Heatmap) {
+		set(c, cell{
+			glyph: byte('0' + level),
+			bg:    grey(level),
+		})
+	}
+
+	for _, c := range opts.Path {
+		set(c, cell{glyph: Path, fg: "#ffffff", bold: true})
+	}
+
+	for _, hz := range b.Hazards {
+		if index.Inside(hz) {
+			cl := &grid[index.Index(hz)]
+			if cl.glyph == Empty {
+				cl.glyph = Hazard
+			}
+			cl.bg = "#5a1e1e"
+		}
+	}
+
+	for _, fd := range b.Food {
+		set(fd, cell{glyph: Food, fg: "#ff5c5c", bold: true})
+	}
+
+	for i, sn := range b.Snakes {
+		head, color := snakeGlyph(b, i, opts), snakeColor(sn, i, opts)
+		for j := len(sn.Body) - 1; j >= 0; j-- {
+			cl := cell{glyph: head + 'a' - 'A', fg: color}
+			switch {
+			case j == 0:
+				cl.glyph, cl.bold = head, true
+			case j == len(sn.Body)-1:
+				cl.glyph = Tail
+			}
+
+			if index.Inside(sn.Body[j]) {
+				cl.bg = grid[index.Index(sn.Body[j])].bg
+			}
+			set(sn.Body[j], cl)
+		}
+	}
+
+	var sb strings.Builder
+	width := len(strconv.Itoa(b.Height - 1))
+	for y := b.Height - 1; y >= 0; y-- {
+		if opts.Axes {
+			fmt.Fprintf(&sb, "%*d ", width, y)
+		}
+
+		for x := 0; x < b.Width; x++ {
+			cl := grid[index.Index(api.Coord{X: x, Y: y})]
+			if opts.Color {
+				sb.WriteString(ansi(cl))
+				continue
+			}
+
+			sb.WriteByte(cl.glyph)
+		}
+		sb.WriteByte('\n')
+	}
+
+	if opts.Axes {
+		sb.WriteString(strings.Repeat(" ", width+1))
+		for x := 0; x < b.Width; x++ {
+			sb.WriteByte(byte('0' + x%10))
+		}
+		sb.WriteByte('\n')
+	}
+
+	if opts.Legend {
+		for i, sn := range b.Snakes {
+			fmt.Fprintf(&sb, "%c %s health=%d length=%d", snakeGlyph(b, i, opts), sn.Name, sn.Health, len(sn.Body))
+			if sn.Shout != "" {
+				fmt.Fprintf(&sb, " shout=%q", sn.Shout)
+			}
+			sb.WriteByte('\n')
+		}
+	}
+
+	return sb.String()
+}
+
+// Request draws the board of a request from the point of view of the snake
+// it was sent to.
+func Request(sr api.SnakeRequest, opts Options) string {
+	opts.You = sr.You.ID
+	return Text(sr.Board, opts)
+}
+
+// snakeGlyph is the letter for the i'th snake on the board.
+func snakeGlyph(b api.Board, i int, opts Options) byte {
+	if b.Snakes[i].ID == opts.You {
+		return You
+	}
+
+	// skip Y so nobody else looks like you
+	n := 0
+	for _, sn := range b.Snakes[:i] {
+		if sn.ID != opts.You {
+			n++
+		}
+	}
+
+	if n >= int(You-'A') {
+		n++
+	}
+
+	return byte('A' + n%26)
+}
+
+// Colors is the color every snake on b is drawn in, starting from
+// opts.Colors. Palette colors go by the order of snakes on the board, which
+// changes as snakes are eliminated, so games should be drawn with the colors
+// of their first board.
+func Colors(b api.Board, opts Options) map[string]string {
+	result := make(map[string]string, len(b.Snakes))
+	for id, c := range opts.Colors {
+		result[id] = c
+	}
+
+	for i, sn := range b.Snakes {
+		result[sn.ID] = snakeColor(sn, i, opts)
+	}
+
+	return result
+}
+
+// snakeColor is the color of the i'th snake on the board.
+func snakeColor(sn api.Snake, i int, opts Options) string {
+	if c, ok := opts.Colors[sn.ID]; ok {
+		return c
+	}
+
+	if sn.Customizations.Color != "" {
+		return sn.Customizations.Color
+	}
+
+	return snakePalette[i%len(snakePalette)]
+}
+
+// grey is a background shade for heatmap level 0 through 9.
+func grey(level int) string {
+	v := 0x20 + level*0x14
+	return fmt.Sprintf("#%02x%02x%02x", v, v, v)
+}
+
+// ansi draws a cell with 24-bit ANSI colors.
+func ansi(cl cell) string {
+	var sb strings.Builder
+	if cl.bold {
+		sb.WriteString("\x1b[1m")
+	}
+
+	if r, g, b, ok := parseHex(cl.fg); ok {
+		fmt.Fprintf(&sb, "\x1b[38;2;%d;%d;%dm", r, g, b)
+	}
+
+	if r, g, b, ok := parseHex(cl.bg); ok {
+		fmt.Fprintf(&sb, "\x1b[48;2;%d;%d;%dm", r, g, b)
+	}
+
+	sb.WriteByte(cl.glyph)
+	sb.WriteString("\x1b[0m")
+
+	return sb.String()
+}
+
+// parseHex parses a #rrggbb or #rgb color.
+func parseHex(s string) (r, g, b uint8, ok bool) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+
+	if len(s) != 6 {
+		return 0, 0, 0, false
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+
+	return uint8(v >> 16), uint8(v >> 8), uint8(v), true
+}
@@ -0,0 +1,57 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Xe/bsnk/api"
+)
+
+func TestText(t *testing.T) {
+	got := Text(testBoard(), Options{
+		You:    "me",
+		Axes:   true,
+		Legend: true,
+		Path:   []api.Coord{{X: 1, Y: 2}, {X: 2, Y: 2}},
+	})
+
+	want := strings.Join([]string{
+		"3 ~....*",
+		"2 ~oo.A.",
+		"1 .Y..aa",
+		"0 .y+..+",
+		"  012345",
+		"Y pyra health=90 length=3",
+		"A greedy health=50 length=4",
+		"",
+	}, "\n")
+
+	if got != want {
+		t.Errorf("wanted:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestTextHeatmap(t *testing.T) {
+	b := api.Board{Width: 3, Height: 1}
+	got := Text(b, Options{
+		Heatmap: map[api.Coord]float64{{X: 0, Y: 0}: -1, {X: 1, Y: 0}: 0, {X: 2, Y: 0}: 1},
+	})
+
+	if got != "059\n" {
+		t.Errorf("wanted 059, got: %q", got)
+	}
+}
+
+func TestTextColor(t *testing.T) {
+	b := testBoard()
+	b.Snakes[1].Customizations.Color = "#c79dd7"
+
+	got := Text(b, Options{Color: true})
+	if !strings.Contains(got, "\x1b[38;2;199;157;215m") {
+		t.Errorf("snakes should be drawn in their color: %q", got)
+	}
+
+	if !strings.Contains(got, "\x1b[48;2;90;30;30m~") {
+		t.Errorf("hazards should have a background: %q", got)
+	}
+}
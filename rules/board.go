@@ -0,0 +1,141 @@
+package rules
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/Xe/bsnk/api"
+)
+
+// ErrTooManySnakes is returned when there is no room to place every snake.
+var ErrTooManySnakes = errors.New("rules: too many snakes for this board")
+
+// CreateInitialBoard lays out a new board the way the official engine does:
+// snakes start stacked on one of eight spots around the edge of the board
+// with a piece of food diagonally next to them, and one more piece of food
+// goes in the middle. Boards too small for that get random spots.
+func CreateInitialBoard(width, height int, ids []string, rng *rand.Rand) (api.Board, error) {
+	b := api.Board{
+		Width:  width,
+		Height: height,
+	}
+
+	starts, err := startPoints(b, len(ids), rng)
+	if err != nil {
+		return api.Board{}, err
+	}
+
+	for i, id := range ids {
+		sn := api.Snake{
+			ID:     id,
+			Name:   id,
+			Health: SnakeMaxHealth,
+			Head:   starts[i],
+			Length: SnakeStartSize,
+		}
+
+		for j := 0; j < SnakeStartSize; j++ {
+			sn.Body = append(sn.Body, starts[i])
+		}
+
+		b.Snakes = append(b.Snakes, sn)
+	}
+
+	placeFood(&b, rng)
+
+	return b, nil
+}
+
+func startPoints(b api.Board, n int, rng *rand.Rand) ([]api.Coord, error) {
+	if b.Width >= 5 && b.Height >= 5 && n <= 8 {
+		mnX, mdX, mxX := 1, (b.Width-1)/2, b.Width-2
+		mnY, mdY, mxY := 1, (b.Height-1)/2, b.Height-2
+
+		points := []api.Coord{
+			{X: mnX, Y: mnY}, {X: mnX, Y: mdY}, {X: mnX, Y: mxY},
+			{X: mdX, Y: mnY}, {X: mdX, Y: mxY},
+			{X: mxX, Y: mnY}, {X: mxX, Y: mdY}, {X: mxX, Y: mxY},
+		}
+
+		rng.Shuffle(len(points), func(i, j int) {
+			points[i], points[j] = points[j], points[i]
+		})
+
+		return points[:n], nil
+	}
+
+	free := unoccupied(b)
+	if len(free) < n {
+		return nil, ErrTooManySnakes
+	}
+
+	rng.Shuffle(len(free), func(i, j int) {
+		free[i], free[j] = free[j], free[i]
+	})
+
+	return free[:n], nil
+}
+
+// placeFood puts one piece of food diagonally next to every snake, on the
+// side away from the center, and one in the center.
+func placeFood(b *api.Board, rng *rand.Rand) {
+	center := api.Coord{X: (b.Width - 1) / 2, Y: (b.Height - 1) / 2}
+
+	for _, sn := range b.Snakes {
+		head := sn.Body[0]
+
+		var options []api.Coord
+		for _, c := range []api.Coord{
+			{X: head.X - 1, Y: head.Y - 1},
+			{X: head.X - 1, Y: head.Y + 1},
+			{X: head.X + 1, Y: head.Y - 1},
+			{X: head.X + 1, Y: head.Y + 1},
+		} {
+			if !b.Inside(c) || c.Eq(center) || occupied(*b, c) {
+				continue
+			}
+
+			// don't put food between the snake and the center
+			towardsX := abs(c.X-center.X) < abs(head.X-center.X)
+			towardsY := abs(c.Y-center.Y) < abs(head.Y-center.Y)
+			if towardsX && towardsY {
+				continue
+			}
+
+			options = append(options, c)
+		}
+
+		if len(options) > 0 {
+			b.Food = append(b.Food, options[rng.Intn(len(options))])
+		}
+	}
+
+	if !occupied(*b, center) {
+		b.Food = append(b.Food, center)
+	}
+}
+
+// occupied checks if a point has a snake or food on it.
+func occupied(b api.Board, c api.Coord) bool {
+	if isFood(b, c) {
+		return true
+	}
+
+	for _, sn := range b.Snakes {
+		for _, bd := range sn.Body {
+			if bd.Eq(c) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
@@ -0,0 +1,51 @@
+package rules
+
+import "github.com/Xe/bsnk/api"
+
+// Constrictor is the standard ruleset without food: every snake stays at full
+// health and grows every turn.
+type Constrictor struct {
+	*Standard
+}
+
+// Name is the name of the ruleset.
+func (r Constrictor) Name() string {
+	return "constrictor"
+}
+
+// Next advances the board by one turn.
+func (r Constrictor) Next(turn int, prev api.Board, moves []SnakeMove) (api.Board, []Elimination, error) {
+	return run(turn, prev, moves, []stage{
+		r.moveSnakes,
+		r.reduceHealth,
+		r.damageHazards,
+		r.eliminateSnakes,
+		constrict,
+	})
+}
+
+// InitializeBoard removes the starting food.
+func (r Constrictor) InitializeBoard(b api.Board) api.Board {
+	st := &step{board: CopyBoard(b)}
+	constrict(st)
+
+	return st.board
+}
+
+// constrict removes all food and makes sure every snake grows next turn.
+func constrict(st *step) error {
+	st.board.Food = nil
+
+	for i := range st.board.Snakes {
+		sn := &st.board.Snakes[i]
+		sn.Health = SnakeMaxHealth
+
+		// a stacked tail means the snake is already going to grow
+		n := len(sn.Body)
+		if n > 2 && !sn.Body[n-1].Eq(sn.Body[n-2]) {
+			growSnake(sn)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/Xe/bsnk/api"
+)
+
+// Royale is the standard ruleset with a safe zone that shrinks from a random
+// side every Settings.Royale.ShrinkEveryNTurns turns. Everything outside the
+// safe zone is a hazard.
+type Royale struct {
+	*Standard
+
+	// Seed picks the sides the safe zone shrinks from. Like the official
+	// engine, the safe zone is worked out from it and the turn, not from
+	// the hazards already on the board.
+	Seed int64
+}
+
+// Name is the name of the ruleset.
+func (r Royale) Name() string {
+	return "royale"
+}
+
+// Next advances the board by one turn.
+func (r Royale) Next(turn int, prev api.Board, moves []SnakeMove) (api.Board, []Elimination, error) {
+	if r.Settings.Royale.ShrinkEveryNTurns < 1 {
+		return api.Board{}, nil, errors.New("rules: royale must shrink at least every turn")
+	}
+
+	return run(turn, prev, moves, append(r.stages(), r.shrink))
+}
+
+// shrink replaces the hazards with everything outside the safe zone, which
+// has had one side moved in by one for every shrink turn so far.
+func (r Royale) shrink(st *step) error {
+	b := &st.board
+	b.Hazards = nil
+
+	shrinks := st.turn / r.Settings.Royale.ShrinkEveryNTurns
+	if shrinks == 0 {
+		return nil
+	}
+
+	rng := rand.New(rand.NewSource(r.Seed))
+	minX, maxX, minY, maxY := 0, b.Width-1, 0, b.Height-1
+	for i := 0; i < shrinks; i++ {
+		switch rng.Intn(4) {
+		case 0:
+			minX++
+		case 1:
+			maxX--
+		case 2:
+			minY++
+		case 3:
+			maxY--
+		}
+	}
+
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			if x < minX || x > maxX || y < minY || y > maxY {
+				b.Hazards = append(b.Hazards, api.Coord{X: x, Y: y})
+			}
+		}
+	}
+
+	return nil
+}
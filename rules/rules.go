@@ -0,0 +1,194 @@
+// Package rules advances an api.Board by one turn the same way the official
+// Battlesnake game engine does, so games can be played and predicted without
+// a live server.
+package rules
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Xe/bsnk/api"
+)
+
+// Snake defaults from the official engine.
+const (
+	SnakeMaxHealth = 100
+	SnakeStartSize = 3
+)
+
+// Reasons a snake can be eliminated, matching the official engine.
+const (
+	EliminatedByCollision     = "snake-collision"
+	EliminatedBySelfCollision = "snake-self-collision"
+	EliminatedByOutOfHealth   = "out-of-health"
+	EliminatedByHeadToHead    = "head-collision"
+	EliminatedByOutOfBounds   = "wall-collision"
+)
+
+// ErrNoSnakes is returned when a board without any snakes is advanced.
+var ErrNoSnakes = errors.New("rules: board has no snakes")
+
+// SnakeMove is the move one snake made this turn.
+type SnakeMove struct {
+	ID   string
+	Move api.Direction
+}
+
+// Elimination records why a snake was removed from the board.
+type Elimination struct {
+	ID    string `json:"id"`
+	Cause string `json:"cause"`
+
+	// By is the ID of the snake that caused the elimination, if any.
+	By string `json:"by,omitempty"`
+}
+
+// Ruleset is a set of rules that can advance a board.
+type Ruleset interface {
+	// Name is the name of the ruleset as it appears in api.Ruleset.
+	Name() string
+
+	// Next returns the board for turn after every snake has made its move.
+	// Eliminated snakes are removed from the returned board and reported in
+	// the order they were eliminated. The previous board is not modified.
+	Next(turn int, prev api.Board, moves []SnakeMove) (api.Board, []Elimination, error)
+
+	// IsGameOver checks if the game on this board has finished.
+	IsGameOver(b api.Board) bool
+}
+
+// Initializer is implemented by rulesets that change the board before the
+// first turn is played.
+type Initializer interface {
+	InitializeBoard(b api.Board) api.Board
+}
+
+// New creates the ruleset a game is being played with, by name. The seed
+// drives every random decision the ruleset makes.
+func New(rs api.Ruleset, seed int64) (Ruleset, error) {
+	std := NewStandard(rs.Settings, seed)
+
+	switch rs.Name {
+	case "", "standard":
+		return std, nil
+	case "royale":
+		return Royale{Standard: std, Seed: seed}, nil
+	case "constrictor":
+		return Constrictor{std}, nil
+	case "wrapped":
+		return Wrapped{std}, nil
+	case "solo":
+		return Solo{std}, nil
+	case "squad":
+		return Squad{std}, nil
+	}
+
+	return nil, fmt.Errorf("rules: unknown ruleset %q", rs.Name)
+}
+
+// Names is the name of every ruleset New knows about.
+var Names = []string{"standard", "royale", "constrictor", "wrapped", "solo", "squad"}
+
+// InitializeBoard applies any changes r makes to a board before the first
+// turn.
+func InitializeBoard(r Ruleset, b api.Board) api.Board {
+	if init, ok := r.(Initializer); ok {
+		return init.InitializeBoard(b)
+	}
+
+	return b
+}
+
+// DefaultSettings are the settings the official engine uses when a game is
+// created without any.
+func DefaultSettings() api.RulesetSettings {
+	return api.RulesetSettings{
+		FoodSpawnChance:     15,
+		MinimumFood:         1,
+		HazardDamagePerTurn: 14,
+		Royale: api.RoyaleSettings{
+			ShrinkEveryNTurns: 25,
+		},
+	}
+}
+
+// step is the state of a board while a ruleset is advancing it.
+type step struct {
+	turn       int
+	board      api.Board
+	moves      map[string]api.Direction
+	eliminated []Elimination
+
+	// removed are the snakes that were eliminated this turn, as they were
+	// when they were eliminated.
+	removed []api.Snake
+}
+
+// stage is one part of advancing a board, such as moving or feeding snakes.
+type stage func(st *step) error
+
+// run copies prev and advances it through every stage in order.
+func run(turn int, prev api.Board, moves []SnakeMove, stages []stage) (api.Board, []Elimination, error) {
+	if len(prev.Snakes) == 0 {
+		return api.Board{}, nil, ErrNoSnakes
+	}
+
+	st := &step{
+		turn:  turn,
+		board: CopyBoard(prev),
+		moves: make(map[string]api.Direction, len(moves)),
+	}
+
+	for _, m := range moves {
+		st.moves[m.ID] = m.Move
+	}
+
+	for _, s := range stages {
+		if err := s(st); err != nil {
+			return api.Board{}, nil, err
+		}
+	}
+
+	return st.board, st.eliminated, nil
+}
+
+// eliminate removes every snake in elims from the board.
+func (st *step) eliminate(elims []Elimination) {
+	if len(elims) == 0 {
+		return
+	}
+
+	gone := make(map[string]bool, len(elims))
+	for _, e := range elims {
+		gone[e.ID] = true
+	}
+
+	alive := st.board.Snakes[:0]
+	for _, sn := range st.board.Snakes {
+		if gone[sn.ID] {
+			st.removed = append(st.removed, sn)
+			continue
+		}
+
+		alive = append(alive, sn)
+	}
+
+	st.board.Snakes = alive
+	st.eliminated = append(st.eliminated, elims...)
+}
+
+// CopyBoard makes a deep copy of a board so it can be modified without
+// changing the original.
+func CopyBoard(b api.Board) api.Board {
+	result := b
+	result.Food = append([]api.Coord(nil), b.Food...)
+	result.Hazards = append([]api.Coord(nil), b.Hazards...)
+	result.Snakes = make([]api.Snake, len(b.Snakes))
+
+	for i, sn := range b.Snakes {
+		sn.Body = append([]api.Coord(nil), sn.Body...)
+		result.Snakes[i] = sn
+	}
+
+	return result
+}
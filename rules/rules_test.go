@@ -0,0 +1,583 @@
+package rules
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/Xe/bsnk/api"
+)
+
+func snake(id string, health int, body ...api.Coord) api.Snake {
+	return api.Snake{
+		ID:     id,
+		Health: health,
+		Body:   body,
+		Head:   body[0],
+		Length: len(body),
+	}
+}
+
+func noFood() api.RulesetSettings {
+	return api.RulesetSettings{}
+}
+
+func find(b api.Board, id string) (api.Snake, bool) {
+	for _, sn := range b.Snakes {
+		if sn.ID == id {
+			return sn, true
+		}
+	}
+
+	return api.Snake{}, false
+}
+
+func TestStandardMove(t *testing.T) {
+	prev := api.Board{
+		Width:  7,
+		Height: 5,
+		Snakes: []api.Snake{
+			snake("a", 50, api.Coord{X: 1, Y: 1}, api.Coord{X: 1, Y: 0}, api.Coord{X: 0, Y: 0}),
+			snake("b", 50, api.Coord{X: 5, Y: 3}, api.Coord{X: 5, Y: 4}, api.Coord{X: 6, Y: 4}),
+		},
+	}
+
+	r := NewStandard(noFood(), 1)
+	next, elims, err := r.Next(1, prev, []SnakeMove{{ID: "a", Move: api.Up}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(elims) != 0 {
+		t.Fatalf("nobody should die: %v", elims)
+	}
+
+	a, _ := find(next, "a")
+	want := []api.Coord{{X: 1, Y: 2}, {X: 1, Y: 1}, {X: 1, Y: 0}}
+	if !reflect.DeepEqual(a.Body, want) || !a.Head.Eq(want[0]) || a.Health != 49 {
+		t.Errorf("a moved wrong: %#v", a)
+	}
+
+	// b didn't send a move, so it keeps going down
+	b, _ := find(next, "b")
+	if !b.Head.Eq(api.Coord{X: 5, Y: 2}) {
+		t.Errorf("b should have continued down, got: %v", b.Body)
+	}
+
+	if prev.Snakes[0].Body[0].Eq(want[0]) {
+		t.Error("the previous board was modified")
+	}
+}
+
+func TestStandardFeed(t *testing.T) {
+	prev := api.Board{
+		Width:  5,
+		Height: 5,
+		Food:   []api.Coord{{X: 2, Y: 3}, {X: 4, Y: 4}},
+		Snakes: []api.Snake{
+			snake("a", 10, api.Coord{X: 2, Y: 2}, api.Coord{X: 2, Y: 1}, api.Coord{X: 2, Y: 0}),
+		},
+	}
+
+	r := NewStandard(noFood(), 1)
+	next, _, err := r.Next(1, prev, []SnakeMove{{ID: "a", Move: api.Up}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := next.Snakes[0]
+	want := []api.Coord{{X: 2, Y: 3}, {X: 2, Y: 2}, {X: 2, Y: 1}, {X: 2, Y: 1}}
+	if !reflect.DeepEqual(a.Body, want) || a.Health != SnakeMaxHealth || a.Length != 4 {
+		t.Errorf("a wasn't fed: %#v", a)
+	}
+
+	if !reflect.DeepEqual(next.Food, []api.Coord{{X: 4, Y: 4}}) {
+		t.Errorf("food should have been eaten: %v", next.Food)
+	}
+
+	// snakes eat before they starve, so food on the last point of health
+	// is still in time
+	prev.Snakes[0].Health = 1
+	next, elims, err := r.Next(1, prev, []SnakeMove{{ID: "a", Move: api.Up}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(elims) != 0 || len(next.Snakes) != 1 || next.Snakes[0].Health != SnakeMaxHealth {
+		t.Errorf("a should have eaten in time, got eliminations %v and snakes %#v", elims, next.Snakes)
+	}
+}
+
+func TestStandardEliminations(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		snakes []api.Snake
+		moves  []SnakeMove
+		want   []Elimination
+	}{
+		{
+			name: "starvation",
+			snakes: []api.Snake{
+				snake("a", 1, api.Coord{X: 2, Y: 2}, api.Coord{X: 2, Y: 1}, api.Coord{X: 2, Y: 0}),
+			},
+			moves: []SnakeMove{{ID: "a", Move: api.Up}},
+			want:  []Elimination{{ID: "a", Cause: EliminatedByOutOfHealth}},
+		},
+		{
+			name: "wall",
+			snakes: []api.Snake{
+				snake("a", 50, api.Coord{X: 6, Y: 2}, api.Coord{X: 5, Y: 2}, api.Coord{X: 4, Y: 2}),
+			},
+			moves: []SnakeMove{{ID: "a", Move: api.Right}},
+			want:  []Elimination{{ID: "a", Cause: EliminatedByOutOfBounds}},
+		},
+		{
+			name: "self",
+			snakes: []api.Snake{
+				snake("a", 50, api.Coord{X: 2, Y: 2}, api.Coord{X: 2, Y: 1}, api.Coord{X: 3, Y: 1}, api.Coord{X: 3, Y: 2}, api.Coord{X: 3, Y: 3}),
+			},
+			moves: []SnakeMove{{ID: "a", Move: api.Right}},
+			want:  []Elimination{{ID: "a", Cause: EliminatedBySelfCollision, By: "a"}},
+		},
+		{
+			name: "body",
+			snakes: []api.Snake{
+				snake("a", 50, api.Coord{X: 2, Y: 2}, api.Coord{X: 1, Y: 2}, api.Coord{X: 0, Y: 2}),
+				snake("b", 50, api.Coord{X: 3, Y: 4}, api.Coord{X: 3, Y: 3}, api.Coord{X: 3, Y: 2}, api.Coord{X: 3, Y: 1}),
+			},
+			moves: []SnakeMove{{ID: "a", Move: api.Right}, {ID: "b", Move: api.Right}},
+			want:  []Elimination{{ID: "a", Cause: EliminatedByCollision, By: "b"}},
+		},
+		{
+			name: "chasing a tail is safe",
+			snakes: []api.Snake{
+				snake("a", 50, api.Coord{X: 2, Y: 2}, api.Coord{X: 1, Y: 2}, api.Coord{X: 0, Y: 2}),
+				snake("b", 50, api.Coord{X: 3, Y: 4}, api.Coord{X: 3, Y: 3}, api.Coord{X: 3, Y: 2}),
+			},
+			moves: []SnakeMove{{ID: "a", Move: api.Right}, {ID: "b", Move: api.Right}},
+		},
+		{
+			name: "head to head, longer wins",
+			snakes: []api.Snake{
+				snake("a", 50, api.Coord{X: 2, Y: 2}, api.Coord{X: 1, Y: 2}, api.Coord{X: 0, Y: 2}),
+				snake("b", 50, api.Coord{X: 4, Y: 2}, api.Coord{X: 5, Y: 2}, api.Coord{X: 6, Y: 2}, api.Coord{X: 6, Y: 3}),
+			},
+			moves: []SnakeMove{{ID: "a", Move: api.Right}, {ID: "b", Move: api.Left}},
+			want:  []Elimination{{ID: "a", Cause: EliminatedByHeadToHead, By: "b"}},
+		},
+		{
+			name: "head to head, same length",
+			snakes: []api.Snake{
+				snake("a", 50, api.Coord{X: 2, Y: 2}, api.Coord{X: 1, Y: 2}, api.Coord{X: 0, Y: 2}),
+				snake("b", 50, api.Coord{X: 4, Y: 2}, api.Coord{X: 5, Y: 2}, api.Coord{X: 6, Y: 2}),
+			},
+			moves: []SnakeMove{{ID: "a", Move: api.Right}, {ID: "b", Move: api.Left}},
+			want: []Elimination{
+				{ID: "a", Cause: EliminatedByHeadToHead, By: "b"},
+				{ID: "b", Cause: EliminatedByHeadToHead, By: "a"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prev := api.Board{Width: 7, Height: 5, Snakes: tc.snakes}
+
+			r := NewStandard(noFood(), 1)
+			next, elims, err := r.Next(1, prev, tc.moves)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(elims, tc.want) {
+				t.Errorf("wanted eliminations %v, got: %v", tc.want, elims)
+			}
+
+			if len(next.Snakes) != len(tc.snakes)-len(tc.want) {
+				t.Errorf("eliminated snakes should be removed, got: %d snakes", len(next.Snakes))
+			}
+		})
+	}
+}
+
+func TestStandardHazards(t *testing.T) {
+	settings := noFood()
+	settings.HazardDamagePerTurn = 14
+
+	prev := api.Board{
+		Width:   5,
+		Height:  5,
+		Hazards: []api.Coord{{X: 2, Y: 3}, {X: 3, Y: 2}},
+		Food:    []api.Coord{{X: 3, Y: 2}},
+		Snakes: []api.Snake{
+			snake("a", 50, api.Coord{X: 2, Y: 2}, api.Coord{X: 2, Y: 1}, api.Coord{X: 2, Y: 0}),
+		},
+	}
+
+	r := NewStandard(settings, 1)
+
+	next, _, err := r.Next(1, prev, []SnakeMove{{ID: "a", Move: api.Up}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h := next.Snakes[0].Health; h != 50-1-14 {
+		t.Errorf("hazard damage: wanted health %d, got: %d", 50-1-14, h)
+	}
+
+	next, _, err = r.Next(1, prev, []SnakeMove{{ID: "a", Move: api.Right}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h := next.Snakes[0].Health; h != SnakeMaxHealth {
+		t.Errorf("eating in a hazard: wanted health %d, got: %d", SnakeMaxHealth, h)
+	}
+}
+
+func TestStandardSpawnFood(t *testing.T) {
+	settings := api.RulesetSettings{FoodSpawnChance: 50, MinimumFood: 2}
+
+	play := func(seed int64) [][]api.Coord {
+		b := api.Board{
+			Width:  7,
+			Height: 7,
+			Snakes: []api.Snake{
+				snake("a", 100, api.Coord{X: 3, Y: 3}, api.Coord{X: 3, Y: 3}, api.Coord{X: 3, Y: 3}),
+			},
+		}
+
+		r := NewStandard(settings, seed)
+		var food [][]api.Coord
+		dirs := []api.Direction{api.Up, api.Right, api.Down, api.Down, api.Left, api.Left, api.Up, api.Up}
+		for turn, dir := range dirs {
+			var err error
+			b, _, err = r.Next(turn+1, b, []SnakeMove{{ID: "a", Move: dir}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(b.Food) < settings.MinimumFood {
+				t.Fatalf("turn %d: wanted at least %d food, got: %v", turn+1, settings.MinimumFood, b.Food)
+			}
+
+			for _, fd := range b.Food {
+				for _, bd := range b.Snakes[0].Body {
+					if fd.Eq(bd) {
+						t.Fatalf("turn %d: food spawned on the snake at %s", turn+1, fd)
+					}
+				}
+			}
+
+			food = append(food, b.Food)
+		}
+
+		return food
+	}
+
+	if a, b := play(42), play(42); !reflect.DeepEqual(a, b) {
+		t.Errorf("the same seed should spawn the same food:\n%v\n%v", a, b)
+	}
+}
+
+func TestCreateInitialBoard(t *testing.T) {
+	for _, size := range [][2]int{{7, 7}, {11, 11}, {19, 11}, {3, 3}} {
+		ids := []string{"a", "b", "c", "d"}
+		b, err := CreateInitialBoard(size[0], size[1], ids, rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatalf("%v: %v", size, err)
+		}
+
+		if len(b.Snakes) != len(ids) {
+			t.Fatalf("%v: wanted %d snakes, got: %d", size, len(ids), len(b.Snakes))
+		}
+
+		seen := map[api.Coord]bool{}
+		for _, sn := range b.Snakes {
+			if len(sn.Body) != SnakeStartSize || sn.Health != SnakeMaxHealth || !b.Inside(sn.Head) {
+				t.Errorf("%v: bad snake %#v", size, sn)
+			}
+
+			if seen[sn.Head] {
+				t.Errorf("%v: two snakes start at %s", size, sn.Head)
+			}
+			seen[sn.Head] = true
+		}
+
+		for _, fd := range b.Food {
+			if seen[fd] || !b.Inside(fd) {
+				t.Errorf("%v: bad food at %s", size, fd)
+			}
+		}
+	}
+
+	if _, err := CreateInitialBoard(2, 2, []string{"a", "b", "c", "d", "e"}, rand.New(rand.NewSource(1))); err != ErrTooManySnakes {
+		t.Errorf("wanted ErrTooManySnakes, got: %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range Names {
+		r, err := New(api.Ruleset{Name: name}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if r.Name() != name {
+			t.Errorf("wanted %s, got: %s", name, r.Name())
+		}
+	}
+
+	if _, err := New(api.Ruleset{Name: "chess"}, 1); err == nil {
+		t.Error("unknown rulesets should be an error")
+	}
+}
+
+func TestRoyale(t *testing.T) {
+	settings := noFood()
+	settings.Royale.ShrinkEveryNTurns = 2
+
+	r, err := New(api.Ruleset{Name: "royale", Settings: settings}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := api.Board{
+		Width:  7,
+		Height: 7,
+		Snakes: []api.Snake{
+			snake("a", 100, api.Coord{X: 3, Y: 3}, api.Coord{X: 3, Y: 3}, api.Coord{X: 3, Y: 3}),
+		},
+	}
+
+	dirs := []api.Direction{api.Up, api.Right, api.Down, api.Down, api.Left, api.Left}
+	for i, dir := range dirs {
+		turn := i + 1
+		b, _, err = r.Next(turn, b, []SnakeMove{{ID: "a", Move: dir}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		shrinks := turn / 2
+		minX, maxX, minY, maxY := safeZone(b)
+		if size := (maxX - minX + 1) + (maxY - minY + 1); size != 14-shrinks {
+			t.Errorf("turn %d: wanted the safe zone to have shrunk %d times, got x %d..%d y %d..%d", turn, shrinks, minX, maxX, minY, maxY)
+		}
+
+		if want := 49 - (maxX-minX+1)*(maxY-minY+1); len(b.Hazards) != want {
+			t.Errorf("turn %d: wanted %d hazards, got: %d", turn, want, len(b.Hazards))
+		}
+	}
+
+	// hazards the game started with aren't the safe zone, and don't change
+	// where it ends up
+	b.Hazards = []api.Coord{{X: 0, Y: 6}, {X: 1, Y: 6}, {X: 2, Y: 6}, {X: 3, Y: 6}, {X: 4, Y: 6}, {X: 5, Y: 6}, {X: 6, Y: 6}}
+	for _, seed := range []int64{1, 2, 3} {
+		r := Royale{Standard: NewStandard(settings, seed), Seed: seed}
+		clean := b
+		clean.Hazards = nil
+
+		want, _, err := r.Next(2, clean, []SnakeMove{{ID: "a", Move: api.Right}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, _, err := r.Next(2, b, []SnakeMove{{ID: "a", Move: api.Right}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got.Hazards, want.Hazards) || len(got.Hazards) != 7 {
+			t.Errorf("seed %d: wanted the one shrink in %v, got: %v", seed, want.Hazards, got.Hazards)
+		}
+	}
+
+	if _, _, err := (Royale{Standard: NewStandard(noFood(), 1)}).Next(1, b, nil); err == nil {
+		t.Error("royale without a shrink rate should be an error")
+	}
+}
+
+// safeZone returns the bounding box of the cells that are not hazards.
+func safeZone(b api.Board) (minX, maxX, minY, maxY int) {
+	hazards := b.NewGrid()
+	for _, hz := range b.Hazards {
+		hazards.Set(hz, 1)
+	}
+
+	minX, maxX, minY, maxY = b.Width, -1, b.Height, -1
+	for i, v := range hazards.Cells {
+		if v != 0 {
+			continue
+		}
+
+		c := hazards.Coord(i)
+		if c.X < minX {
+			minX = c.X
+		}
+		if c.X > maxX {
+			maxX = c.X
+		}
+		if c.Y < minY {
+			minY = c.Y
+		}
+		if c.Y > maxY {
+			maxY = c.Y
+		}
+	}
+
+	return minX, maxX, minY, maxY
+}
+
+func TestConstrictor(t *testing.T) {
+	r, err := New(api.Ruleset{Name: "constrictor", Settings: DefaultSettings()}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := CreateInitialBoard(7, 7, []string{"a"}, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = InitializeBoard(r, b)
+
+	if len(b.Food) != 0 {
+		t.Errorf("constrictor boards have no food: %v", b.Food)
+	}
+
+	start := b.Snakes[0].Body[0]
+	dir := api.Up
+	if start.Y > 3 {
+		dir = api.Down
+	}
+
+	for turn := 1; turn <= 3; turn++ {
+		b, _, err = r.Next(turn, b, []SnakeMove{{ID: "a", Move: dir}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sn := b.Snakes[0]
+		if sn.Health != SnakeMaxHealth || len(b.Food) != 0 {
+			t.Errorf("turn %d: health %d, food %v", turn, sn.Health, b.Food)
+		}
+
+		// the starting stack has to unwind before the snake visibly grows
+		if got := len(sn.Body); got != SnakeStartSize+turn-1 {
+			t.Errorf("turn %d: wanted length %d, got: %d", turn, SnakeStartSize+turn-1, got)
+		}
+	}
+}
+
+func TestWrapped(t *testing.T) {
+	r, err := New(api.Ruleset{Name: "wrapped"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := api.Board{
+		Width:  7,
+		Height: 5,
+		Snakes: []api.Snake{
+			snake("a", 50, api.Coord{X: 6, Y: 2}, api.Coord{X: 5, Y: 2}, api.Coord{X: 4, Y: 2}),
+			snake("b", 50, api.Coord{X: 2, Y: 0}, api.Coord{X: 2, Y: 1}, api.Coord{X: 2, Y: 2}),
+		},
+	}
+
+	next, elims, err := r.Next(1, prev, []SnakeMove{{ID: "a", Move: api.Right}, {ID: "b", Move: api.Down}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(elims) != 0 {
+		t.Fatalf("nobody should hit a wall: %v", elims)
+	}
+
+	a, _ := find(next, "a")
+	b, _ := find(next, "b")
+	if !a.Head.Eq(api.Coord{X: 0, Y: 2}) || !b.Head.Eq(api.Coord{X: 2, Y: 4}) {
+		t.Errorf("snakes should wrap around, got a at %s and b at %s", a.Head, b.Head)
+	}
+}
+
+func TestSolo(t *testing.T) {
+	r, err := New(api.Ruleset{Name: "solo"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := api.Board{
+		Width:  5,
+		Height: 5,
+		Snakes: []api.Snake{snake("a", 50, api.Coord{X: 2, Y: 2})},
+	}
+
+	if r.IsGameOver(b) {
+		t.Error("solo games go on while one snake is alive")
+	}
+
+	if !r.IsGameOver(api.Board{}) {
+		t.Error("solo games are over when every snake is dead")
+	}
+}
+
+func TestSquad(t *testing.T) {
+	settings := noFood()
+	settings.Squad = api.SquadSettings{
+		AllowBodyCollisions: true,
+		SharedElimination:   true,
+		SharedHealth:        true,
+		SharedLength:        true,
+	}
+
+	r, err := New(api.Ruleset{Name: "squad", Settings: settings}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	squad := func(sn api.Snake, squad string) api.Snake {
+		sn.Squad = squad
+		return sn
+	}
+
+	prev := api.Board{
+		Width:  9,
+		Height: 9,
+		Snakes: []api.Snake{
+			// a runs through its squad mate b
+			squad(snake("a", 50, api.Coord{X: 2, Y: 2}, api.Coord{X: 1, Y: 2}, api.Coord{X: 0, Y: 2}), "red"),
+			squad(snake("b", 80, api.Coord{X: 3, Y: 4}, api.Coord{X: 3, Y: 3}, api.Coord{X: 3, Y: 2}, api.Coord{X: 3, Y: 1}), "red"),
+			// c runs into the wall and takes d with it
+			squad(snake("c", 50, api.Coord{X: 8, Y: 8}, api.Coord{X: 7, Y: 8}, api.Coord{X: 6, Y: 8}), "blue"),
+			squad(snake("d", 50, api.Coord{X: 6, Y: 6}, api.Coord{X: 6, Y: 5}, api.Coord{X: 6, Y: 4}), "blue"),
+		},
+	}
+
+	next, elims, err := r.Next(1, prev, []SnakeMove{
+		{ID: "a", Move: api.Right},
+		{ID: "b", Move: api.Up},
+		{ID: "c", Move: api.Right},
+		{ID: "d", Move: api.Up},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Elimination{
+		{ID: "c", Cause: EliminatedByOutOfBounds},
+		{ID: "d", Cause: EliminatedBySquad, By: "c"},
+	}
+	if !reflect.DeepEqual(elims, want) {
+		t.Errorf("wanted eliminations %v, got: %v", want, elims)
+	}
+
+	a, ok := find(next, "a")
+	if !ok {
+		t.Fatal("a should be able to pass through b")
+	}
+
+	if a.Health != 79 || len(a.Body) != 4 {
+		t.Errorf("a should share b's health and length, got health %d length %d", a.Health, len(a.Body))
+	}
+
+	if !r.IsGameOver(next) {
+		t.Error("the game should be over with only one squad left")
+	}
+}
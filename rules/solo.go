@@ -0,0 +1,19 @@
+package rules
+
+import "github.com/Xe/bsnk/api"
+
+// Solo is the standard ruleset for practising alone: the game only ends
+// once every snake is dead.
+type Solo struct {
+	*Standard
+}
+
+// Name is the name of the ruleset.
+func (r Solo) Name() string {
+	return "solo"
+}
+
+// IsGameOver checks if every snake has been eliminated.
+func (r Solo) IsGameOver(b api.Board) bool {
+	return len(b.Snakes) == 0
+}
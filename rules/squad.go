@@ -0,0 +1,102 @@
+package rules
+
+import "github.com/Xe/bsnk/api"
+
+// EliminatedBySquad is the reason a snake is eliminated when one of its
+// squad mates is eliminated and the squad shares eliminations.
+const EliminatedBySquad = "squad-eliminated"
+
+// Squad is the standard ruleset played in teams. Depending on the squad
+// settings, snakes on the same squad can pass through each other and share
+// eliminations, length and health.
+type Squad struct {
+	*Standard
+}
+
+// Name is the name of the ruleset.
+func (r Squad) Name() string {
+	return "squad"
+}
+
+// Next advances the board by one turn.
+func (r Squad) Next(turn int, prev api.Board, moves []SnakeMove) (api.Board, []Elimination, error) {
+	return run(turn, prev, moves, []stage{
+		r.moveSnakes,
+		r.reduceHealth,
+		r.damageHazards,
+		r.feedSnakes,
+		r.spawnFood,
+		r.eliminateSnakes,
+		r.share,
+	})
+}
+
+// IsGameOver checks if only one squad is left.
+func (r Squad) IsGameOver(b api.Board) bool {
+	if len(b.Snakes) == 0 {
+		return true
+	}
+
+	for _, sn := range b.Snakes {
+		if !sameSquad(sn, b.Snakes[0]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r Squad) eliminateSnakes(st *step) error {
+	if !r.Settings.Squad.AllowBodyCollisions {
+		return eliminateSnakes(st, nil)
+	}
+
+	return eliminateSnakes(st, sameSquad)
+}
+
+// share spreads eliminations, health and length across each squad.
+func (r Squad) share(st *step) error {
+	settings := r.Settings.Squad
+
+	if settings.SharedElimination {
+		var elims []Elimination
+		for _, sn := range st.board.Snakes {
+			for _, dead := range st.removed {
+				if sameSquad(sn, dead) {
+					elims = append(elims, Elimination{ID: sn.ID, Cause: EliminatedBySquad, By: dead.ID})
+					break
+				}
+			}
+		}
+		st.eliminate(elims)
+	}
+
+	for i := range st.board.Snakes {
+		sn := &st.board.Snakes[i]
+		for _, other := range st.board.Snakes {
+			if other.ID == sn.ID || !sameSquad(*sn, other) {
+				continue
+			}
+
+			if settings.SharedHealth && other.Health > sn.Health {
+				sn.Health = other.Health
+			}
+
+			for settings.SharedLength && len(sn.Body) < len(other.Body) {
+				growSnake(sn)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sameSquad checks if two snakes are on the same team. Snakes without a
+// squad are on a team of their own.
+func sameSquad(sn, other api.Snake) bool {
+	if sn.ID == other.ID {
+		return true
+	}
+
+	return sn.Squad != "" && sn.Squad == other.Squad
+}
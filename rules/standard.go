@@ -0,0 +1,284 @@
+package rules
+
+import (
+	"math/rand"
+
+	"github.com/Xe/bsnk/api"
+)
+
+// Standard is the standard Battlesnake ruleset.
+type Standard struct {
+	Settings api.RulesetSettings
+
+	// Rand decides where and when food spawns. Seed it to make games
+	// reproducible.
+	Rand *rand.Rand
+}
+
+// NewStandard creates a standard ruleset with its own seeded RNG.
+func NewStandard(settings api.RulesetSettings, seed int64) *Standard {
+	return &Standard{
+		Settings: settings,
+		Rand:     rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Name is the name of the ruleset.
+func (r *Standard) Name() string {
+	return "standard"
+}
+
+// Next advances the board by one turn.
+func (r *Standard) Next(turn int, prev api.Board, moves []SnakeMove) (api.Board, []Elimination, error) {
+	return run(turn, prev, moves, r.stages())
+}
+
+// IsGameOver checks if at most one snake is left.
+func (r *Standard) IsGameOver(b api.Board) bool {
+	return len(b.Snakes) <= 1
+}
+
+// stages are the steps of a standard turn, in the order the official engine
+// runs them.
+func (r *Standard) stages() []stage {
+	return []stage{
+		r.moveSnakes,
+		r.reduceHealth,
+		r.damageHazards,
+		r.feedSnakes,
+		r.spawnFood,
+		r.eliminateSnakes,
+	}
+}
+
+// moveFor returns the move a snake made. Snakes that didn't send a valid
+// move keep going the way they were going, or up if they can't tell.
+func moveFor(st *step, sn api.Snake) api.Direction {
+	if dir := st.moves[sn.ID]; dir.Valid() {
+		return dir
+	}
+
+	if len(sn.Body) >= 2 {
+		if dir := sn.Body[1].Dir(sn.Body[0]); dir.Valid() {
+			return dir
+		}
+	}
+
+	return api.Up
+}
+
+func (r *Standard) moveSnakes(st *step) error {
+	for i := range st.board.Snakes {
+		sn := &st.board.Snakes[i]
+		head := moveFor(st, *sn).Apply(sn.Body[0])
+
+		copy(sn.Body[1:], sn.Body[:len(sn.Body)-1])
+		sn.Body[0] = head
+		sn.Head = head
+		sn.Length = len(sn.Body)
+	}
+
+	return nil
+}
+
+func (r *Standard) reduceHealth(st *step) error {
+	for i := range st.board.Snakes {
+		st.board.Snakes[i].Health--
+	}
+
+	return nil
+}
+
+func (r *Standard) damageHazards(st *step) error {
+	if r.Settings.HazardDamagePerTurn <= 0 {
+		return nil
+	}
+
+	for i := range st.board.Snakes {
+		sn := &st.board.Snakes[i]
+		head := sn.Body[0]
+
+		// eating food in a hazard cancels out the damage
+		if !st.board.IsHazard(head) || isFood(st.board, head) {
+			continue
+		}
+
+		sn.Health -= r.Settings.HazardDamagePerTurn
+		if sn.Health < 0 {
+			sn.Health = 0
+		}
+	}
+
+	return nil
+}
+
+func (r *Standard) eliminateSnakes(st *step) error {
+	return eliminateSnakes(st, nil)
+}
+
+// eliminateSnakes removes snakes that starved, hit a wall or collided with
+// something. Collisions are decided against the board as it was after
+// movement, so two snakes can eliminate each other. If allowed is non-nil,
+// body collisions it returns true for are ignored.
+func eliminateSnakes(st *step, allowed func(sn, other api.Snake) bool) error {
+	var elims []Elimination
+	for _, sn := range st.board.Snakes {
+		switch {
+		case sn.Health <= 0:
+			elims = append(elims, Elimination{ID: sn.ID, Cause: EliminatedByOutOfHealth})
+		case !st.board.Inside(sn.Body[0]):
+			elims = append(elims, Elimination{ID: sn.ID, Cause: EliminatedByOutOfBounds})
+		}
+	}
+	st.eliminate(elims)
+
+	elims = nil
+	for _, sn := range st.board.Snakes {
+		if hasBodyCollided(sn, sn) {
+			elims = append(elims, Elimination{ID: sn.ID, Cause: EliminatedBySelfCollision, By: sn.ID})
+			continue
+		}
+
+		if by, ok := collidedWith(st.board, sn, hasBodyCollided, allowed); ok {
+			elims = append(elims, Elimination{ID: sn.ID, Cause: EliminatedByCollision, By: by})
+			continue
+		}
+
+		if by, ok := collidedWith(st.board, sn, hasLostHeadToHead, nil); ok {
+			elims = append(elims, Elimination{ID: sn.ID, Cause: EliminatedByHeadToHead, By: by})
+		}
+	}
+	st.eliminate(elims)
+
+	return nil
+}
+
+// collidedWith returns the ID of the first other snake that collided
+// reports sn ran into.
+func collidedWith(b api.Board, sn api.Snake, collided func(sn, other api.Snake) bool, allowed func(sn, other api.Snake) bool) (string, bool) {
+	for _, other := range b.Snakes {
+		if other.ID == sn.ID || !collided(sn, other) {
+			continue
+		}
+
+		if allowed != nil && allowed(sn, other) {
+			continue
+		}
+
+		return other.ID, true
+	}
+
+	return "", false
+}
+
+// hasBodyCollided checks if the head of sn is inside the body of other.
+func hasBodyCollided(sn, other api.Snake) bool {
+	head := sn.Body[0]
+	for _, bd := range other.Body[1:] {
+		if head.Eq(bd) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasLostHeadToHead checks if sn ran head first into a snake at least as
+// long as it is.
+func hasLostHeadToHead(sn, other api.Snake) bool {
+	return sn.Body[0].Eq(other.Body[0]) && len(sn.Body) <= len(other.Body)
+}
+
+func (r *Standard) feedSnakes(st *step) error {
+	remaining := st.board.Food[:0]
+	for _, fd := range st.board.Food {
+		eaten := false
+		for i := range st.board.Snakes {
+			sn := &st.board.Snakes[i]
+			if sn.Body[0].Eq(fd) {
+				feedSnake(sn)
+				eaten = true
+			}
+		}
+
+		if !eaten {
+			remaining = append(remaining, fd)
+		}
+	}
+
+	st.board.Food = remaining
+	return nil
+}
+
+// feedSnake restores a snake's health and grows it by one segment.
+func feedSnake(sn *api.Snake) {
+	sn.Health = SnakeMaxHealth
+	growSnake(sn)
+}
+
+// growSnake stacks a new segment on the snake's tail.
+func growSnake(sn *api.Snake) {
+	sn.Body = append(sn.Body, sn.Body[len(sn.Body)-1])
+	sn.Length = len(sn.Body)
+}
+
+func (r *Standard) spawnFood(st *step) error {
+	spawnFood(st.board, r.Settings, r.Rand, &st.board.Food)
+	return nil
+}
+
+// spawnFood tops the board up to the minimum amount of food, or otherwise
+// has a chance of dropping one more piece.
+func spawnFood(b api.Board, settings api.RulesetSettings, rng *rand.Rand, food *[]api.Coord) {
+	n := 0
+	switch {
+	case len(b.Food) < settings.MinimumFood:
+		n = settings.MinimumFood - len(b.Food)
+	case settings.FoodSpawnChance > 0 && rng.Intn(100) < settings.FoodSpawnChance:
+		n = 1
+	}
+
+	for ; n > 0; n-- {
+		free := unoccupied(b)
+		if len(free) == 0 {
+			return
+		}
+
+		*food = append(*food, free[rng.Intn(len(free))])
+		b.Food = *food
+	}
+}
+
+// unoccupied returns every point on the board without a snake or food on it,
+// in a stable order so seeded games stay reproducible.
+func unoccupied(b api.Board) []api.Coord {
+	taken := b.NewGrid()
+	for _, fd := range b.Food {
+		taken.Set(fd, 1)
+	}
+
+	for _, sn := range b.Snakes {
+		for _, bd := range sn.Body {
+			taken.Set(bd, 1)
+		}
+	}
+
+	var result []api.Coord
+	for i, v := range taken.Cells {
+		if v == 0 {
+			result = append(result, taken.Coord(i))
+		}
+	}
+
+	return result
+}
+
+func isFood(b api.Board, c api.Coord) bool {
+	for _, fd := range b.Food {
+		if fd.Eq(c) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,49 @@
+package rules
+
+import "github.com/Xe/bsnk/api"
+
+// Wrapped is the standard ruleset on a board whose edges loop around: leaving
+// one side of the board puts a snake on the opposite side.
+type Wrapped struct {
+	*Standard
+}
+
+// Name is the name of the ruleset.
+func (r Wrapped) Name() string {
+	return "wrapped"
+}
+
+// Next advances the board by one turn.
+func (r Wrapped) Next(turn int, prev api.Board, moves []SnakeMove) (api.Board, []Elimination, error) {
+	stages := r.stages()
+	stages[0] = r.moveSnakes
+
+	return run(turn, prev, moves, stages)
+}
+
+func (r Wrapped) moveSnakes(st *step) error {
+	if err := r.Standard.moveSnakes(st); err != nil {
+		return err
+	}
+
+	for i := range st.board.Snakes {
+		sn := &st.board.Snakes[i]
+		sn.Body[0] = Wrap(st.board, sn.Body[0])
+		sn.Head = sn.Body[0]
+	}
+
+	return nil
+}
+
+// Wrap moves a point that fell off one edge of the board onto the opposite
+// edge.
+func Wrap(b api.Board, c api.Coord) api.Coord {
+	return api.Coord{
+		X: mod(c.X, b.Width),
+		Y: mod(c.Y, b.Height),
+	}
+}
+
+func mod(x, n int) int {
+	return ((x % n) + n) % n
+}
@@ -0,0 +1,470 @@
+package snakes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/bitboard"
+	"within.website/ln"
+)
+
+// Ahri looks ahead. It runs a paranoid alpha-beta search over the standard
+// rules: it picks its move, then the other snakes pick theirs as if they
+// were all out to get it, and the moves are made together. Searching deeper
+// one turn at a time, it keeps going for as long as the move deadline
+// allows.
+//
+// Struct members are configuration flags for the snake behavior.
+type Ahri struct {
+	// MaxDepth is how many turns ahead Ahri looks at most.
+	MaxDepth int `json:"max_depth"`
+
+	// MaxOpponents is how many of the closest other snakes are searched.
+	// The rest are guessed to make their first safe move, which keeps big
+	// games from branching too much to see anything.
+	MaxOpponents int `json:"max_opponents"`
+
+	// TableSize is how many positions are remembered between searches in
+	// each game.
+	TableSize int `json:"table_size"`
+
+	// Weights are how much each feature counts for when scoring positions
+	// at the end of the search (see Features).
+	Weights Weights `json:"weights"`
+
+	// Eval scores positions at the end of the search instead of Weights,
+	// if set.
+	Eval Evaluator `json:"-"`
+
+	// gameStates holds an ahriState for every game being played.
+	gameStates
+}
+
+// ahriWin is the score of a won game; a lost one is -ahriWin. Both are
+// moved towards zero by how many turns away they are, so a quick win beats
+// a slow one and a slow loss beats a quick one.
+const ahriWin = 1e5
+
+// errDecided stops deepening once the result is known.
+var errDecided = errors.New("ahri: game decided")
+
+type ahriState struct {
+	table *bitboard.Table
+	last  ahriResult
+}
+
+// ahriResult is what the last search of a game found.
+type ahriResult struct {
+	Move  api.Direction `json:"move"`
+	Score float64       `json:"score"`
+	Depth int           `json:"depth"`
+	Nodes int           `json:"nodes"`
+}
+
+func init() {
+	Register(Brain{
+		Name: "ahri",
+		Config: func() interface{} {
+			return &Ahri{MaxDepth: 30, MaxOpponents: 2, TableSize: 1 << 16, Weights: DefaultWeights()}
+		},
+		New: func(config interface{}) (api.AI, error) {
+			a := config.(*Ahri)
+			switch {
+			case a.MaxDepth < 1:
+				return nil, fmt.Errorf("ahri: max_depth must be at least 1, got %d", a.MaxDepth)
+			case a.MaxOpponents < 0:
+				return nil, fmt.Errorf("ahri: max_opponents must not be negative, got %d", a.MaxOpponents)
+			case a.TableSize < 2:
+				return nil, fmt.Errorf("ahri: table_size must be at least 2, got %d", a.TableSize)
+			}
+
+			// an Eval set in code wins over the weights
+			if a.Eval == nil {
+				eval, err := NewWeightedEval(a.Weights)
+				if err != nil {
+					return nil, fmt.Errorf("ahri: weights: %w", err)
+				}
+				a.Eval = eval
+			}
+
+			return a, nil
+		},
+	})
+}
+
+func (*Ahri) Ping() (*api.PingResponse, error) {
+	return &api.PingResponse{
+		APIVersion: "1",
+		Color:      "#d1477a",
+		HeadType:   "fang",
+		TailType:   "sharp",
+	}, nil
+}
+
+func (a *Ahri) store() *api.StateStore {
+	return a.states("ahri")
+}
+
+// Start starts a game.
+func (a *Ahri) Start(ctx context.Context, sr api.SnakeRequest) error {
+	a.store().PutTurn(api.KeyOf(sr), sr.Turn, ahriState{table: bitboard.NewTable(a.TableSize, bitboard.TwoTier)})
+
+	return nil
+}
+
+// Move responds with the snake's movements for a given Turn.
+func (a *Ahri) Move(ctx context.Context, sr api.SnakeRequest) (*api.MoveResponse, error) {
+	s := bitboard.FromRequest(sr)
+	if s.You < 0 {
+		return &api.MoveResponse{Move: api.SafestMove(sr)}, nil
+	}
+
+	v, _ := a.store().Get(api.KeyOf(sr))
+	st, ok := v.(ahriState)
+	if !ok {
+		st.table = bitboard.NewTable(a.TableSize, bitboard.TwoTier)
+	}
+	st.table.Age()
+
+	ctx, cancel := api.Trim(ctx)
+	defer cancel()
+
+	search := newAhriSearch(s, a.eval(), st.table, a.MaxOpponents)
+	result := ahriResult{Move: api.SafestMove(sr)}
+
+	var buf [4]api.Direction
+	if moves := s.Moves(s.You, buf[:0]); len(moves) > 1 {
+		_, err := api.Deepen(ctx, a.MaxDepth, func(ctx context.Context, depth int) error {
+			search.ctx = ctx
+			score, move, err := search.max(depth, 0, math.Inf(-1), math.Inf(1))
+			if err != nil {
+				return err
+			}
+
+			result = ahriResult{Move: move, Score: score, Depth: depth}
+			if math.Abs(score) > ahriWin/2 {
+				return errDecided
+			}
+
+			return nil
+		})
+		if err != nil && err != errDecided && err != context.DeadlineExceeded {
+			return nil, err
+		}
+	} else if len(moves) == 1 {
+		result.Move = moves[0]
+	}
+	result.Nodes = search.nodes
+
+	st.last = result
+	a.store().PutTurn(api.KeyOf(sr), sr.Turn, st)
+
+	ln.Log(ctx, ln.Info("searched"), ln.F{
+		"search_depth": result.Depth,
+		"search_nodes": result.Nodes,
+		"search_score": result.Score,
+		"search_move":  result.Move,
+	})
+	// how the position the move leads to looks, if the others make their
+	// first safe moves
+	if e, ok := a.eval().(Explainer); ok {
+		NewPosition(s, s.You).After(result.Move, func(p *Position) {
+			ln.Log(ctx, ln.Info("evaluated"), e.Explain(p).F())
+		})
+	}
+	api.Annotate(ctx, "search", fmt.Sprintf("depth %d, score %.1f, %d nodes", result.Depth, result.Score, result.Nodes))
+
+	return &api.MoveResponse{Move: result.Move}, nil
+}
+
+// End ends a game.
+func (a *Ahri) End(ctx context.Context, sr api.SnakeRequest) error {
+	a.store().Delete(api.KeyOf(sr))
+
+	return nil
+}
+
+// Debug shows what the last search found in every game Ahri is playing, by
+// game and snake ID.
+func (a *Ahri) Debug() interface{} {
+	result := map[string]ahriResult{}
+	a.store().Each(func(key api.GameKey, v interface{}) bool {
+		result[key.GameID+"/"+key.SnakeID] = v.(ahriState).last
+		return true
+	})
+
+	return result
+}
+
+func (a *Ahri) eval() Evaluator {
+	if a.Eval == nil {
+		return DefaultEval
+	}
+
+	return a.Eval
+}
+
+// ahriSearch is one search of one position, made and unmade in place.
+type ahriSearch struct {
+	ctx   context.Context
+	s     *bitboard.State
+	pos   *Position
+	eval  Evaluator
+	table *bitboard.Table
+
+	// opponents are searched; others are guessed.
+	opponents []int
+	others    []int
+	rivals    bool
+
+	// joint holds the moves being tried for every snake, by ply.
+	joint [][]api.Direction
+
+	// history counts, by snake and square moved into, how often a move
+	// was good enough to cut a search short. Moves that often are tried
+	// first.
+	history [][]int
+
+	nodes int
+}
+
+func newAhriSearch(s *bitboard.State, eval Evaluator, table *bitboard.Table, maxOpponents int) *ahriSearch {
+	a := &ahriSearch{
+		ctx:     context.Background(),
+		s:       s,
+		pos:     NewPosition(s, s.You),
+		eval:    eval,
+		table:   table,
+		history: make([][]int, len(s.Snakes)),
+	}
+
+	head := s.Coord(s.Snakes[s.You].Head())
+	var rivals []int
+	for i := range s.Snakes {
+		a.history[i] = make([]int, s.Cells)
+		if i != s.You && s.Snakes[i].Alive && s.Snakes[i].Head() >= 0 {
+			rivals = append(rivals, i)
+		}
+	}
+	a.rivals = len(rivals) > 0
+
+	sort.SliceStable(rivals, func(i, j int) bool {
+		return api.Line{A: head, B: s.Coord(s.Snakes[rivals[i]].Head())}.Manhattan() <
+			api.Line{A: head, B: s.Coord(s.Snakes[rivals[j]].Head())}.Manhattan()
+	})
+	if len(rivals) > maxOpponents {
+		a.opponents, a.others = rivals[:maxOpponents], rivals[maxOpponents:]
+	} else {
+		a.opponents = rivals
+	}
+
+	return a
+}
+
+// max searches depth turns ahead for the best move for us, ply turns into
+// the search.
+func (a *ahriSearch) max(depth, ply int, alpha, beta float64) (float64, api.Direction, error) {
+	a.nodes++
+	if a.nodes&1023 == 0 && a.late() {
+		return 0, api.NoDirection, context.DeadlineExceeded
+	}
+
+	me := a.s.You
+	if !a.s.Snakes[me].Alive {
+		return -ahriWin + float64(ply), api.NoDirection, nil
+	}
+	if a.rivals && a.s.Alive() == 1 {
+		return ahriWin - float64(ply), api.NoDirection, nil
+	}
+	if depth == 0 {
+		a.pos.Moved()
+		return a.eval.Evaluate(a.pos), api.NoDirection, nil
+	}
+
+	var hint api.Direction
+	if e, ok := a.table.Probe(a.s.Hash); ok {
+		hint = e.Move
+		if score := fromTable(e.Score, ply); ply > 0 && e.Depth >= depth {
+			switch {
+			case e.Bound == bitboard.Exact,
+				e.Bound == bitboard.Lower && score >= beta,
+				e.Bound == bitboard.Upper && score <= alpha:
+				return score, e.Move, nil
+			}
+		}
+	}
+
+	var buf [4]api.Direction
+	moves := a.order(me, a.s.Moves(me, buf[:0]), hint)
+	if len(moves) == 0 {
+		// nowhere to go, so whatever we do we are dead next turn
+		moves = append(moves, api.NoDirection)
+	}
+
+	joint := a.jointAt(ply)
+	start, best, bestMove := alpha, math.Inf(-1), moves[0]
+	for _, m := range moves {
+		joint[me] = m
+		score, err := a.min(depth, ply, 0, alpha, beta)
+		if err != nil {
+			return 0, api.NoDirection, err
+		}
+
+		if score > best {
+			best, bestMove = score, m
+		}
+		if best > alpha {
+			alpha = best
+		}
+		if alpha >= beta {
+			a.cutoff(me, m, depth)
+			break
+		}
+	}
+
+	bound := bitboard.Exact
+	switch {
+	case best <= start:
+		bound = bitboard.Upper
+	case best >= beta:
+		bound = bitboard.Lower
+	}
+	a.table.Store(a.s.Hash, bitboard.Entry{Score: toTable(best, ply), Depth: depth, Bound: bound, Move: bestMove})
+
+	return best, bestMove, nil
+}
+
+// toTable is score as it is stored in the table. Wins and losses count
+// turns from the root of the search, but the same position can come up at
+// any ply and in later searches, so in the table they count turns from the
+// position instead.
+func toTable(score float64, ply int) float32 {
+	switch {
+	case score > ahriWin/2:
+		score += float64(ply)
+	case score < -ahriWin/2:
+		score -= float64(ply)
+	}
+
+	return float32(score)
+}
+
+// fromTable undoes toTable for a position ply turns into the search.
+func fromTable(score float32, ply int) float64 {
+	result := float64(score)
+	switch {
+	case result > ahriWin/2:
+		result -= float64(ply)
+	case result < -ahriWin/2:
+		result += float64(ply)
+	}
+
+	return result
+}
+
+// min has the k-th opponent pick the move that is worst for us, knowing our
+// move, then the one after it, and then makes all the moves together.
+func (a *ahriSearch) min(depth, ply, k int, alpha, beta float64) (float64, error) {
+	joint := a.joint[ply]
+	if k == len(a.opponents) {
+		a.guess(joint)
+		a.s.Make(joint)
+		score, _, err := a.max(depth-1, ply+1, alpha, beta)
+		a.s.Unmake()
+
+		return score, err
+	}
+
+	i := a.opponents[k]
+	var buf [4]api.Direction
+	moves := a.order(i, a.s.Moves(i, buf[:0]), api.NoDirection)
+	if len(moves) == 0 {
+		// dead, or will be whatever it does
+		joint[i] = api.NoDirection
+		return a.min(depth, ply, k+1, alpha, beta)
+	}
+
+	best := math.Inf(1)
+	for _, m := range moves {
+		joint[i] = m
+		score, err := a.min(depth, ply, k+1, alpha, beta)
+		if err != nil {
+			return 0, err
+		}
+
+		if score < best {
+			best = score
+		}
+		if best < beta {
+			beta = best
+		}
+		if alpha >= beta {
+			a.cutoff(i, m, depth)
+			break
+		}
+	}
+
+	return best, nil
+}
+
+// guess picks moves for the snakes that aren't searched: the first safe
+// one, if there is one.
+func (a *ahriSearch) guess(joint []api.Direction) {
+	for _, i := range a.others {
+		var buf [4]api.Direction
+		joint[i] = api.NoDirection
+		if moves := a.s.Moves(i, buf[:0]); len(moves) > 0 {
+			joint[i] = moves[0]
+		}
+	}
+}
+
+// order sorts moves for snake i best first: the hint, then by history.
+func (a *ahriSearch) order(i int, moves []api.Direction, hint api.Direction) []api.Direction {
+	head := a.s.Snakes[i].Head()
+	rank := func(m api.Direction) int {
+		if m == hint {
+			return math.MaxInt32
+		}
+
+		return a.history[i][a.s.Neighbors[head][m-1]]
+	}
+
+	// at most four moves, so insertion sort
+	for j := 1; j < len(moves); j++ {
+		for k := j; k > 0 && rank(moves[k]) > rank(moves[k-1]); k-- {
+			moves[k], moves[k-1] = moves[k-1], moves[k]
+		}
+	}
+
+	return moves
+}
+
+// cutoff notes that snake i moving m cut a search depth turns deep short.
+func (a *ahriSearch) cutoff(i int, m api.Direction, depth int) {
+	if !m.Valid() {
+		return
+	}
+
+	if next := a.s.Neighbors[a.s.Snakes[i].Head()][m-1]; next >= 0 {
+		a.history[i][next] += depth * depth
+	}
+}
+
+// late reports whether the search is out of time.
+func (a *ahriSearch) late() bool {
+	return api.Late(a.ctx)
+}
+
+// jointAt is the moves being tried ply turns into the search.
+func (a *ahriSearch) jointAt(ply int) []api.Direction {
+	for len(a.joint) <= ply {
+		a.joint = append(a.joint, make([]api.Direction, len(a.s.Snakes)))
+	}
+
+	return a.joint[ply]
+}
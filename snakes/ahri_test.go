@@ -0,0 +1,54 @@
+package snakes
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestAhri(t *testing.T) {
+	for _, tc := range lookaheadCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			// without a deadline, the search goes as deep as it is allowed
+			// to however busy the machine is
+			b, _ := Lookup("ahri")
+			ai, err := b.Make(json.RawMessage(`{"max_depth": 6}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mr, err := ai.Move(context.Background(), tc.sr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			last := ai.(*Ahri).Debug().(map[string]ahriResult)[tc.sr.Game.ID+"/me"]
+			if mr.Move != tc.want {
+				t.Errorf("wanted %s, got %s after searching %+v", tc.want, mr.Move, last)
+			}
+
+			if last.Depth < 2 {
+				t.Errorf("wanted a search deeper than one turn, got %+v", last)
+			}
+		})
+	}
+
+	// a win 2 turns after a position 3 turns in is 9 turns away when the
+	// position comes up again 7 turns in
+	if got := fromTable(toTable(ahriWin-5, 3), 7); got != ahriWin-9 {
+		t.Errorf("wins should be stored counting from the position, got %v", got)
+	}
+
+	b, _ := Lookup("ahri")
+	config := b.Config().(*Ahri)
+	eval := mustWeightedEval(Weights{"health": 1})
+	config.Eval = eval
+	ai, err := b.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ai.(*Ahri).Eval != Evaluator(eval) {
+		t.Error("an Eval that was set should be kept")
+	}
+}
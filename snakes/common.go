@@ -1,6 +1,8 @@
 package snakes
 
 import (
+	"sync"
+
 	"github.com/Xe/bsnk/api"
 	"github.com/prettymuchbryce/goeasystar"
 )
@@ -16,65 +18,57 @@ const (
 	Edge      = 40
 )
 
-func makePathfinder(decoded api.SnakeRequest) ([][]int, *goeasystar.Pathfinder) {
+// makePathfinder builds an A* pathfinder over the board. Tiles next to
+// snake bodies are not walkable, except for the ones our head can move
+// into, and the likely next positions of enemy heads are avoided. Edges are
+// marked but stay walkable, so targets on them can still be reached.
+func makePathfinder(decoded api.SnakeRequest) (api.Grid, *goeasystar.Pathfinder) {
 	pf := goeasystar.NewPathfinder()
 	pf.DisableCornerCutting()
 	pf.DisableDiagonals()
-	pf.SetAcceptableTiles([]int{Nothing, Risky})
-
-	var grid [][]int
-	grid = make([][]int, decoded.Board.Height)
-	for i := range grid {
-		grid[i] = make([]int, decoded.Board.Width)
+	pf.SetAcceptableTiles([]int{Nothing, Risky, Edge})
 
-		for j := range grid[i] {
-			grid[i][j] = Nothing
+	grid := decoded.Board.NewGrid()
+	for i := range grid.Cells {
+		pt := grid.Coord(i)
+		grid.Cells[i] = Nothing
 
-			if i == 0 || j == 0 {
-				grid[i][j] = Edge
-			}
+		if pt.X == 0 || pt.Y == 0 || pt.X == grid.Width-1 || pt.Y == grid.Height-1 {
+			grid.Cells[i] = Edge
 		}
 	}
 
 	for _, sk := range decoded.Board.Snakes {
 		for _, pt := range sk.Body {
-			grid[pt.X][pt.Y] = SnakeBody
-
-			for _, st := range []api.Coord{
-				pt.Up(),
-				pt.Left(),
-				pt.Right(),
-				pt.Down(),
-			} {
-				if decoded.Board.Inside(st) {
-					grid[st.X][st.Y] = SnakeBody
-				}
+			grid.Set(pt, SnakeBody)
+
+			for _, st := range pt.Neighbors() {
+				grid.Set(st, SnakeBody)
 			}
 		}
 	}
 
-	pf.SetGrid(grid)
+	// without these no path would ever leave our head
+	for _, st := range decoded.You.Head.Neighbors() {
+		if !decoded.Board.IsDeadly(st) {
+			grid.Set(st, Risky)
+		}
+	}
+
+	pf.SetGrid(grid.Rows())
 
 	for _, sk := range decoded.Board.Snakes {
-		var headDir string
+		var headDir api.Direction
 		var theirNext api.Coord
 		if len(sk.Body) < 2 {
 			goto skipHead
 		}
 		headDir = sk.Body[1].Dir(sk.Body[0])
 		{
-			switch headDir {
-			case "left":
-				theirNext = sk.Body[0].Left()
-			case "right":
-				theirNext = sk.Body[0].Right()
-			case "up":
-				theirNext = sk.Body[0].Up()
-			case "down":
-				theirNext = sk.Body[0].Down()
-			default:
+			if !headDir.Valid() {
 				goto skipHead
 			}
+			theirNext = headDir.Apply(sk.Body[0])
 			if decoded.Board.Inside(theirNext) {
 				pf.AvoidAdditionalPoint(theirNext.X, theirNext.Y)
 			}
@@ -88,3 +82,35 @@ func makePathfinder(decoded api.SnakeRequest) ([][]int, *goeasystar.Pathfinder)
 
 	return grid, pf
 }
+
+// gameStates is embedded by brains that keep state for every game they play.
+// Their StateStore is labelled with the name they are served under, and
+// forgets every game when they are retired. Games they missed the start of,
+// or that were evicted, start over.
+type gameStates struct {
+	name      string
+	games     api.StateStore
+	gamesOnce sync.Once
+}
+
+// SetName labels the brain's metrics with the name it is served under.
+func (g *gameStates) SetName(name string) {
+	g.name = name
+}
+
+// Retire forgets every game, once the brain isn't served any more.
+func (g *gameStates) Retire() {
+	g.games.Close()
+}
+
+// states is the brain's StateStore, labelled brain if SetName wasn't called.
+func (g *gameStates) states(brain string) *api.StateStore {
+	g.gamesOnce.Do(func() {
+		g.games.Name = brain
+		if g.name != "" {
+			g.games.Name = g.name
+		}
+	})
+
+	return &g.games
+}
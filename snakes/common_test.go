@@ -0,0 +1,159 @@
+package snakes
+
+import (
+	"testing"
+
+	"github.com/Xe/bsnk/api"
+)
+
+// wideRequest is a request on a board that is much wider than it is tall, so
+// any mix-up between X and Y either panics or puts things in the wrong place.
+func wideRequest() api.SnakeRequest {
+	me := api.Snake{
+		ID:     "me",
+		Health: 90,
+		Body:   []api.Coord{{X: 2, Y: 2}, {X: 1, Y: 2}, {X: 1, Y: 1}},
+	}
+
+	sr := api.SnakeRequest{
+		Board: api.Board{
+			Width:  11,
+			Height: 5,
+			Food:   []api.Coord{{X: 8, Y: 2}},
+			Snakes: []api.Snake{me},
+		},
+		You: me,
+	}
+	sr.Normalize()
+
+	return sr
+}
+
+func TestMakePathfinderNonSquare(t *testing.T) {
+	sr := wideRequest()
+	grid, pf := makePathfinder(sr)
+
+	if grid.Width != 11 || grid.Height != 5 {
+		t.Fatalf("wanted an 11x5 grid, got %dx%d", grid.Width, grid.Height)
+	}
+
+	for _, c := range []api.Coord{{X: 10, Y: 2}, {X: 5, Y: 4}, {X: 0, Y: 4}, {X: 5, Y: 0}} {
+		if grid.At(c) != Edge {
+			t.Errorf("%s should be an edge, got: %d", c, grid.At(c))
+		}
+	}
+
+	for _, c := range []api.Coord{{X: 2, Y: 2}, {X: 1, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 3}} {
+		if grid.At(c) != SnakeBody {
+			t.Errorf("%s should be marked as snake, got: %d", c, grid.At(c))
+		}
+	}
+
+	for _, c := range []api.Coord{{X: 3, Y: 2}, {X: 2, Y: 3}, {X: 2, Y: 1}} {
+		if grid.At(c) != Risky {
+			t.Errorf("%s is a move we can make and should be risky, got: %d", c, grid.At(c))
+		}
+	}
+
+	if grid.At(api.Coord{X: 6, Y: 2}) != Nothing {
+		t.Errorf("(6,2) should be open, got: %d", grid.At(api.Coord{X: 6, Y: 2}))
+	}
+
+	path, err := pf.FindPath(4, 2, 8, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pt := range path {
+		if pt.Y != 2 {
+			t.Errorf("expected a straight path along y=2, got a step at (%d,%d)", pt.X, pt.Y)
+		}
+	}
+
+	// from the head all the way to the far edge
+	if _, err := pf.FindPath(2, 2, 10, 2); err != nil {
+		t.Errorf("wanted a path from the head to the edge, got: %v", err)
+	}
+}
+
+// nonSquareCase is a snake next to food on the edge of a board that isn't
+// square, placed so that mixing up X and Y sends it off the board.
+type nonSquareCase struct {
+	name string
+	sr   api.SnakeRequest
+	want api.Direction
+}
+
+func nonSquareCases() []nonSquareCase {
+	request := func(width, height int, food api.Coord, body ...api.Coord) api.SnakeRequest {
+		me := api.Snake{ID: "me", Health: 90, Body: body}
+		sr := api.SnakeRequest{
+			Board: api.Board{Width: width, Height: height, Food: []api.Coord{food}, Snakes: []api.Snake{me}},
+			You:   me,
+		}
+		sr.Normalize()
+
+		return sr
+	}
+
+	return []nonSquareCase{
+		{
+			name: "7x11",
+			sr:   request(7, 11, api.Coord{X: 6, Y: 9}, api.Coord{X: 3, Y: 9}, api.Coord{X: 3, Y: 8}, api.Coord{X: 3, Y: 7}),
+			want: api.Right,
+		},
+		{
+			name: "11x7",
+			sr:   request(11, 7, api.Coord{X: 9, Y: 6}, api.Coord{X: 9, Y: 3}, api.Coord{X: 8, Y: 3}, api.Coord{X: 7, Y: 3}),
+			want: api.Up,
+		},
+	}
+}
+
+// lookaheadCase is a position only a brain that looks ahead gets right.
+type lookaheadCase struct {
+	name string
+	sr   api.SnakeRequest
+	want api.Direction
+}
+
+func lookaheadCases() []lookaheadCase {
+	request := func(name string, me, them api.Snake, food ...api.Coord) api.SnakeRequest {
+		sr := api.SnakeRequest{
+			Game:  api.Game{ID: name},
+			Turn:  10,
+			Board: api.Board{Width: 7, Height: 7, Food: food, Snakes: []api.Snake{me, them}},
+			You:   me,
+		}
+		sr.Normalize()
+
+		return sr
+	}
+
+	snake := func(id string, health int, body ...api.Coord) api.Snake {
+		return api.Snake{ID: id, Health: health, Body: body}
+	}
+
+	return []lookaheadCase{
+		{
+			// left is safe for now, but the corner is walled off by
+			// their body for longer than we can wait
+			name: "pocket",
+			sr: request("pocket",
+				snake("me", 90, api.Coord{X: 2, Y: 0}, api.Coord{X: 3, Y: 0}, api.Coord{X: 4, Y: 0}, api.Coord{X: 5, Y: 0}, api.Coord{X: 6, Y: 0}, api.Coord{X: 6, Y: 1}, api.Coord{X: 6, Y: 2}, api.Coord{X: 6, Y: 3}),
+				snake("them", 90, api.Coord{X: 4, Y: 3}, api.Coord{X: 3, Y: 3}, api.Coord{X: 2, Y: 3}, api.Coord{X: 1, Y: 3}, api.Coord{X: 1, Y: 2}, api.Coord{X: 1, Y: 1}, api.Coord{X: 0, Y: 1}, api.Coord{X: 0, Y: 2}, api.Coord{X: 0, Y: 3}, api.Coord{X: 0, Y: 4}),
+			),
+			want: api.Up,
+		},
+		{
+			// on the last point of health, only the food saves us
+			name: "starving",
+			sr: request("starving",
+				snake("me", 1, api.Coord{X: 3, Y: 3}, api.Coord{X: 3, Y: 2}, api.Coord{X: 3, Y: 1}),
+				snake("them", 90, api.Coord{X: 0, Y: 6}, api.Coord{X: 1, Y: 6}, api.Coord{X: 2, Y: 6}),
+				api.Coord{X: 2, Y: 3},
+			),
+			want: api.Left,
+		},
+	}
+}
@@ -2,6 +2,7 @@ package snakes
 
 import (
 	"context"
+	"math/rand"
 
 	"github.com/Xe/bsnk/api"
 )
@@ -9,6 +10,10 @@ import (
 // Erratic is a particularly terrible AI.
 type Erratic struct{}
 
+func init() {
+	stateless("erratic", Erratic{})
+}
+
 func (Erratic) Ping() (*api.PingResponse, error) {
 	return &api.PingResponse{
 		APIVersion: "1",
@@ -21,19 +26,20 @@ func (Erratic) Start(ctx context.Context, gs api.SnakeRequest) error {
 	return nil
 }
 
-// Move twitches around.
+// Move twitches around, though not into pockets it can't get out of.
 func (Erratic) Move(ctx context.Context, gs api.SnakeRequest) (*api.MoveResponse, error) {
 	me := gs.You.Body
-	var pickDir string
-
-	for place := range map[api.Coord]struct{}{
-		me[0].Up():    struct{}{},
-		me[0].Down():  struct{}{},
-		me[0].Left():  struct{}{},
-		me[0].Right(): struct{}{},
-	} {
-		if gs.Board.Inside(place) && !gs.Board.IsDeadly(place) {
-			pickDir = me[0].Dir(place)
+	// if nothing better turns up, take the move least likely to kill us
+	pickDir := api.SafestMove(gs)
+	room := 0
+
+	for _, i := range rand.Perm(len(api.Directions)) {
+		place := api.Directions[i].Apply(me[0])
+		if n := gs.Board.Reachable(place, len(me)); n > room {
+			pickDir, room = api.Directions[i], n
+		}
+
+		if room >= len(me) {
 			break
 		}
 	}
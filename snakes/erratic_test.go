@@ -0,0 +1,25 @@
+package snakes
+
+import (
+	"context"
+	"testing"
+)
+
+func TestErraticNonSquare(t *testing.T) {
+	for _, tc := range nonSquareCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			// it moves at random, so give it a few goes at walking into
+			// something
+			for i := 0; i < 20; i++ {
+				mr, err := Erratic{}.Move(context.Background(), tc.sr)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if next := mr.Move.Apply(tc.sr.You.Head); tc.sr.Board.IsDeadly(next) {
+					t.Fatalf("moved %s into %s", mr.Move, next)
+				}
+			}
+		})
+	}
+}
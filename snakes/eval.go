@@ -0,0 +1,319 @@
+package snakes
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/bitboard"
+	"within.website/ln"
+)
+
+// Position is a position for an Evaluator to score, from the point of view
+// of one snake. It keeps work that several features need, such as
+// territory, so it is only done once per position.
+type Position struct {
+	*bitboard.State
+
+	// Me is the index in Snakes of the snake the position is scored for.
+	Me int
+
+	territory bitboard.Territory
+	distances bitboard.Distances
+	computed  struct{ territory, distances bool }
+}
+
+// NewPosition makes a position of s for the snake at index me.
+func NewPosition(s *bitboard.State, me int) *Position {
+	return &Position{State: s, Me: me}
+}
+
+// Moved tells p the state has changed, so anything it worked out is stale.
+func (p *Position) Moved() {
+	p.computed.territory, p.computed.distances = false, false
+}
+
+// Territory is which snake gets to each square first.
+func (p *Position) Territory() *bitboard.Territory {
+	if !p.computed.territory {
+		p.territory.Compute(p.State)
+		p.computed.territory = true
+	}
+
+	return &p.territory
+}
+
+// Distances is how far Me is from each square.
+func (p *Position) Distances() *bitboard.Distances {
+	if !p.computed.distances {
+		p.distances.Compute(p.State, p.Me)
+		p.computed.distances = true
+	}
+
+	return &p.distances
+}
+
+// After makes the move dir for Me, with every other snake making its first
+// safe move, calls fn on the position that leads to, and unmakes it again.
+// It is for heuristic brains that look one move ahead; Me may not survive
+// the move.
+func (p *Position) After(dir api.Direction, fn func(p *Position)) {
+	joint := make([]api.Direction, len(p.Snakes))
+	for i := range p.Snakes {
+		var buf [4]api.Direction
+		if moves := p.Moves(i, buf[:0]); len(moves) > 0 {
+			joint[i] = moves[0]
+		}
+	}
+	joint[p.Me] = dir
+
+	p.Make(joint)
+	p.Moved()
+	defer func() {
+		p.Unmake()
+		p.Moved()
+	}()
+
+	fn(p)
+}
+
+// Evaluator scores positions that aren't over yet. Higher is better for
+// Position.Me. Scores should stay well inside ±1e5, which search brains use
+// for games won and lost.
+type Evaluator interface {
+	Evaluate(p *Position) float64
+}
+
+// EvalFunc lets an ordinary function be an Evaluator.
+type EvalFunc func(p *Position) float64
+
+func (f EvalFunc) Evaluate(p *Position) float64 {
+	return f(p)
+}
+
+// Features are the things about a position an evaluation can weigh, by
+// name. Each is measured for Position.Me, which should be alive.
+var Features = map[string]func(p *Position) float64{
+	// health is from 0 to 1, full.
+	"health": func(p *Position) float64 {
+		return float64(p.Snakes[p.Me].Health) / bitboard.MaxHealth
+	},
+
+	// length_difference is how many segments longer than the longest
+	// other snake Me is, or Me's length if there are none.
+	"length_difference": func(p *Position) float64 {
+		longest := 0
+		for i := range p.Snakes {
+			if sn := &p.Snakes[i]; i != p.Me && sn.Alive && sn.Length > longest {
+				longest = sn.Length
+			}
+		}
+
+		return float64(p.Snakes[p.Me].Length - longest)
+	},
+
+	// reachable_area is the share of the board Me can get to at all.
+	"reachable_area": func(p *Position) float64 {
+		return float64(p.Distances().Reached) / float64(p.Cells)
+	},
+
+	// territory is the share of the board Me gets to before anyone else.
+	"territory": func(p *Position) float64 {
+		return float64(p.Territory().Squares[p.Me]) / float64(p.Cells)
+	},
+
+	// food_distance is how many turns away the closest food Me can get to
+	// is, or the number of squares on the board if there is none.
+	"food_distance": func(p *Position) float64 {
+		d, closest := p.Distances(), p.Cells
+		p.Food.Each(func(c int) {
+			if t := d.Turns[c]; t >= 0 && t < closest {
+				closest = t
+			}
+		})
+
+		return float64(closest)
+	},
+
+	// hazard_exposure is the share of Me's health the square its head is
+	// in takes away each turn.
+	"hazard_exposure": func(p *Position) float64 {
+		if head := p.Snakes[p.Me].Head(); head >= 0 && p.Hazards.Has(head) {
+			return float64(p.HazardDamage) / bitboard.MaxHealth
+		}
+
+		return 0
+	},
+
+	// center_distance is how far Me's head is from the middle of the board,
+	// from 0 in the middle to 1 in a corner.
+	"center_distance": func(p *Position) float64 {
+		head := p.Snakes[p.Me].Head()
+		if head < 0 {
+			return 1
+		}
+
+		c := p.Coord(head)
+		dx, dy := 2*c.X-(p.Width-1), 2*c.Y-(p.Height-1)
+		if dx < 0 {
+			dx = -dx
+		}
+		if dy < 0 {
+			dy = -dy
+		}
+
+		return float64(dx+dy) / float64(p.Width+p.Height-2)
+	},
+
+	// tail_reachable is 1 if Me can follow its own tail, which means it
+	// can always get out of wherever it is, and 0 otherwise.
+	"tail_reachable": func(p *Position) float64 {
+		me := &p.Snakes[p.Me]
+		if tail := me.Tail(); me.Alive && tail >= 0 && tail != me.Head() && p.Distances().Turns[tail] >= 0 {
+			return 1
+		}
+
+		return 0
+	},
+}
+
+// FeatureNames lists every feature in order.
+func FeatureNames() []string {
+	result := make([]string, 0, len(Features))
+	for name := range Features {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// Weights are how much each feature counts for, by name. Features that
+// aren't named don't count.
+type Weights map[string]float64
+
+// DefaultWeights are weights that play reasonably: room first, then
+// length, then not starving.
+func DefaultWeights() Weights {
+	return Weights{
+		"health":            10,
+		"length_difference": 5,
+		"reachable_area":    50,
+		"territory":         100,
+		"food_distance":     -0.5,
+		"hazard_exposure":   -20,
+		"center_distance":   -2,
+		"tail_reachable":    20,
+	}
+}
+
+// Validate makes sure every weight is for a feature that exists.
+func (w Weights) Validate() error {
+	for name := range w {
+		if Features[name] == nil {
+			return fmt.Errorf("unknown feature %q, wanted one of %v", name, FeatureNames())
+		}
+	}
+
+	return nil
+}
+
+// Explainer is an Evaluator that can say how it came to a score.
+type Explainer interface {
+	Evaluator
+	Explain(p *Position) Contributions
+}
+
+// WeightedEval scores a position as the weighted sum of its features.
+type WeightedEval struct {
+	names   []string
+	weights []float64
+	values  []func(p *Position) float64
+}
+
+// NewWeightedEval makes an evaluator from weights.
+func NewWeightedEval(w Weights) (*WeightedEval, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	e := &WeightedEval{}
+	for _, name := range FeatureNames() {
+		if weight := w[name]; weight != 0 {
+			e.names = append(e.names, name)
+			e.weights = append(e.weights, weight)
+			e.values = append(e.values, Features[name])
+		}
+	}
+
+	return e, nil
+}
+
+func (e *WeightedEval) Evaluate(p *Position) float64 {
+	score := 0.0
+	for i, value := range e.values {
+		score += e.weights[i] * value(p)
+	}
+
+	return score
+}
+
+// Explain breaks down how p is scored, feature by feature.
+func (e *WeightedEval) Explain(p *Position) Contributions {
+	result := make(Contributions, len(e.values))
+	for i, value := range e.values {
+		result[i] = Contribution{Feature: e.names[i], Value: value(p), Weight: e.weights[i]}
+	}
+
+	return result
+}
+
+// Contribution is how much one feature counted for in a score.
+type Contribution struct {
+	Feature string
+	Value   float64
+	Weight  float64
+}
+
+// Score is how much the feature added to the score.
+func (c Contribution) Score() float64 {
+	return c.Value * c.Weight
+}
+
+// Contributions are every feature's part of a score.
+type Contributions []Contribution
+
+// Total is the score.
+func (cs Contributions) Total() float64 {
+	total := 0.0
+	for _, c := range cs {
+		total += c.Score()
+	}
+
+	return total
+}
+
+// F logs each feature's part of the score as eval_<feature>, and the score
+// as eval_total.
+func (cs Contributions) F() ln.F {
+	f := ln.F{"eval_total": cs.Total()}
+	for _, c := range cs {
+		f["eval_"+c.Feature] = c.Score()
+	}
+
+	return f
+}
+
+// DefaultEval is a WeightedEval with DefaultWeights.
+var DefaultEval Evaluator = defaultEval
+
+var defaultEval = mustWeightedEval(DefaultWeights())
+
+func mustWeightedEval(w Weights) *WeightedEval {
+	e, err := NewWeightedEval(w)
+	if err != nil {
+		panic(err)
+	}
+
+	return e
+}
@@ -0,0 +1,43 @@
+package snakes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Xe/bsnk/bitboard"
+)
+
+func TestWeightedEval(t *testing.T) {
+	s := bitboard.FromRequest(wideRequest())
+	p := NewPosition(s, s.You)
+
+	want := map[string]float64{
+		"health":            0.9,
+		"length_difference": 3,
+		"reachable_area":    54.0 / 55,
+		"territory":         1,
+		"food_distance":     6,
+		"hazard_exposure":   0,
+		"center_distance":   6.0 / 14,
+		"tail_reachable":    1,
+	}
+	for _, name := range FeatureNames() {
+		if got := Features[name](p); math.Abs(got-want[name]) > 1e-9 {
+			t.Errorf("%s: wanted %g, got %g", name, want[name], got)
+		}
+	}
+
+	e, err := NewWeightedEval(Weights{"health": 10, "food_distance": -1, "territory": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := e.Explain(p)
+	if len(cs) != 2 || math.Abs(cs.Total()-3) > 1e-9 || cs.Total() != e.Evaluate(p) {
+		t.Errorf("wanted health and food distance to score 3, got %+v", cs)
+	}
+
+	if _, err := NewWeightedEval(Weights{"helth": 1}); err == nil {
+		t.Errorf("wanted an unknown feature refused")
+	}
+}
@@ -9,6 +9,10 @@ import (
 // Garen spins to win.
 type Garen struct{}
 
+func init() {
+	stateless("garen", Garen{})
+}
+
 func (Garen) Ping() (*api.PingResponse, error) {
 	return &api.PingResponse{
 		APIVersion: "1",
@@ -23,7 +27,7 @@ func (Garen) Start(ctx context.Context, sr api.SnakeRequest) error {
 
 // Move spins to win.
 func (Garen) Move(ctx context.Context, sr api.SnakeRequest) (*api.MoveResponse, error) {
-	directions := []string{"up", "left", "down", "right"}
+	directions := []api.Direction{api.Up, api.Left, api.Down, api.Right}
 	pickDir := directions[sr.Turn%len(directions)]
 	return &api.MoveResponse{
 		Move: pickDir,
@@ -12,6 +12,10 @@ import (
 // it can.
 type Greedy struct{}
 
+func init() {
+	stateless("greedy", Greedy{})
+}
+
 func (Greedy) Ping() (*api.PingResponse, error) {
 	return &api.PingResponse{
 		APIVersion: "1",
@@ -27,24 +31,32 @@ func (Greedy) Start(ctx context.Context, gs api.SnakeRequest) error {
 // Move responds with the snake's movements for a given Turn.
 func (g Greedy) Move(ctx context.Context, decoded api.SnakeRequest) (*api.MoveResponse, error) {
 	me := decoded.You.Body
-	var pickDir string
+	// if nothing better turns up, take the move least likely to kill us
+	pickDir := api.SafestMove(decoded)
 
 	_, pf := makePathfinder(decoded)
 	target := selectGreedy(decoded)
 
 	ln.WithF(ctx, logCoords("target", target))
 	ln.Log(ctx, ln.Info("found_target"))
+	api.Annotate(ctx, "target", nil, target)
+
+	if cps := decoded.Board.ArticulationPoints(); len(cps) > 0 {
+		api.Annotate(ctx, "chokepoints", nil, cps...)
+	}
 
+	// the food isn't worth it if going for it leaves us boxed in
 	path, _ := pf.FindPath(me[0].X, me[0].Y, target.X, target.Y)
-	if len(path) >= 2 {
+	if len(path) >= 2 && decoded.Board.HasRoom(api.Coord{X: path[1].X, Y: path[1].Y}, len(me)) {
 		pickDir = me[0].Dir(api.Coord{
 			X: path[1].X,
 			Y: path[1].Y,
 		})
 	} else {
-		for _, place := range []api.Coord{me[0].Up(), me[0].Down(), me[0].Left(), me[0].Right()} {
-			if !decoded.Board.IsDeadly(place) {
-				pickDir = me[0].Dir(place)
+		room := 0
+		for _, dir := range api.Directions {
+			if n := decoded.Board.Reachable(dir.Apply(me[0]), len(me)); n > room {
+				pickDir, room = dir, n
 			}
 		}
 	}
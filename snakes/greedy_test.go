@@ -0,0 +1,21 @@
+package snakes
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGreedyNonSquare(t *testing.T) {
+	for _, tc := range nonSquareCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			mr, err := Greedy{}.Move(context.Background(), tc.sr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if mr.Move != tc.want {
+				t.Errorf("wanted %s towards the food on the edge, got: %s", tc.want, mr.Move)
+			}
+		})
+	}
+}
@@ -2,8 +2,11 @@ package snakes
 
 import (
 	"context"
+	"fmt"
+	"math"
 
 	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/bitboard"
 	"github.com/prettymuchbryce/goeasystar"
 	"within.website/ln"
 	"within.website/ln/opname"
@@ -13,16 +16,63 @@ import (
 //
 // Struct memebers are configuration flags for the snake behavior.
 type Pyra struct {
-	MinLength int
+	// MinLength is how long Pyra wants to be before it stops going out of
+	// its way for food.
+	MinLength int `json:"min_length"`
 
-	targets map[string]pyraState
+	// Scores are how much Pyra wants each kind of target. If all zero,
+	// DefaultPyraScores are used.
+	Scores PyraScores `json:"scores"`
+
+	// Weights are how much each feature counts for in how good a position
+	// is (see Features). The position the first step towards a target
+	// leads to is added to the target's score, so targets down dead ends
+	// are wanted less, and without a target Pyra picks the move that
+	// leaves it best off.
+	Weights Weights `json:"weights"`
+
+	eval *WeightedEval
+
+	// gameStates holds a pyraState for every game being played.
+	gameStates
+}
+
+// PyraScores are how much Pyra wants each kind of target. The closer a
+// target is, the more it is wanted.
+type PyraScores struct {
+	// Food is food, when Pyra is long enough and not hungry.
+	Food int `json:"food"`
+
+	// ShortFood is food, when Pyra is shorter than MinLength.
+	ShortFood int `json:"short_food"`
+
+	// HungryFood is food, when Pyra has 30 health or less.
+	HungryFood int `json:"hungry_food"`
+
+	// Tail is Pyra's own tail.
+	Tail int `json:"tail"`
+
+	// Head is the head of a snake shorter than Pyra.
+	Head int `json:"head"`
 }
 
+// DefaultPyraScores are the scores Pyra plays with unless told otherwise.
+func DefaultPyraScores() PyraScores {
+	return PyraScores{Food: 20, ShortFood: 50, HungryFood: 9000, Tail: 50, Head: 400}
+}
+
+// pyraSlack is how much worse than the best move the step towards a target
+// may leave Pyra off before the target is passed up, unless it is hungry.
+const pyraSlack = 5
+
 type pyraTarget struct {
 	api.Line
 
 	Score       int
 	AstarLength int
+
+	// first is the first step of the path to the target.
+	first api.Direction
 }
 
 type pyraState struct {
@@ -30,6 +80,16 @@ type pyraState struct {
 	trg  *pyraTarget
 }
 
+// coords is the path left to follow.
+func (st pyraState) coords() []api.Coord {
+	path := make([]api.Coord, len(st.path))
+	for i, pt := range st.path {
+		path[i] = api.Coord{X: pt.X, Y: pt.Y}
+	}
+
+	return path
+}
+
 func (pt pyraTarget) F() ln.F {
 	f := ln.F{
 		"target_score":        pt.Score,
@@ -42,7 +102,34 @@ func (pt pyraTarget) F() ln.F {
 	return f
 }
 
-func (Pyra) Ping() (*api.PingResponse, error) {
+func init() {
+	Register(Brain{
+		Name: "pyra",
+		Config: func() interface{} {
+			return &Pyra{
+				MinLength: 8,
+				Scores:    DefaultPyraScores(),
+				Weights:   DefaultWeights(),
+			}
+		},
+		New: func(config interface{}) (api.AI, error) {
+			p := config.(*Pyra)
+			if p.MinLength < 0 {
+				return nil, fmt.Errorf("pyra: min_length must not be negative, got %d", p.MinLength)
+			}
+
+			eval, err := NewWeightedEval(p.Weights)
+			if err != nil {
+				return nil, fmt.Errorf("pyra: weights: %w", err)
+			}
+			p.eval = eval
+
+			return p, nil
+		},
+	})
+}
+
+func (*Pyra) Ping() (*api.PingResponse, error) {
 	return &api.PingResponse{
 		APIVersion: "1",
 		Color:      "#5ce8c3",
@@ -53,15 +140,15 @@ func (Pyra) Ping() (*api.PingResponse, error) {
 
 // Start starts a game.
 func (p *Pyra) Start(ctx context.Context, gs api.SnakeRequest) error {
-	if p.targets == nil {
-		p.targets = map[string]pyraState{}
-	}
-
-	p.targets[gs.Game.ID] = p.getState(ctx, gs)
+	p.store().PutTurn(api.KeyOf(gs), gs.Turn, p.getState(ctx, gs))
 
 	return nil
 }
 
+func (p *Pyra) store() *api.StateStore {
+	return p.states("pyra")
+}
+
 func (p *Pyra) getState(ctx context.Context, sr api.SnakeRequest) pyraState {
 	me := sr.You.Body
 
@@ -79,46 +166,132 @@ func (p *Pyra) getState(ctx context.Context, sr api.SnakeRequest) pyraState {
 // Move responds with the snake's movements for a given Turn.
 func (p *Pyra) Move(ctx context.Context, decoded api.SnakeRequest) (*api.MoveResponse, error) {
 	me := decoded.You.Body
-	var pickDir string
+	// if nothing better turns up, take the move least likely to kill us
+	pickDir := api.SafestMove(decoded)
 
-	st := p.targets[decoded.Game.ID]
+	// the board moves under a path, so it's planned afresh every turn
+	st := p.getState(ctx, decoded)
 
-	if len(st.path) < 2 {
-		st = p.getState(ctx, decoded)
+	var pos *Position
+	if s := bitboard.FromRequest(decoded); s.You >= 0 {
+		pos = NewPosition(s, s.You)
 	}
+	eval := p.evaluator()
+
+	// score every move we can make, so a target that costs us too much
+	// can be passed up
+	best, evals := math.Inf(-1), map[api.Direction]float64{}
+	for _, dir := range []api.Direction{api.Up, api.Left, api.Right, api.Down} {
+		coord := dir.Apply(me[0])
+		if !decoded.Board.Inside(coord) || decoded.Board.IsDeadly(coord) {
+			continue
+		}
+
+		if pos == nil {
+			pickDir = dir
+			break
+		}
 
-	if len(st.path) < 2 {
-		for _, coord := range []api.Coord{me[0].Up(), me[0].Left(), me[0].Right(), me[0].Down()} {
-			if decoded.Board.Inside(coord) && !decoded.Board.IsDeadly(coord) {
-				pickDir = me[0].Dir(coord)
-				break
+		pos.After(dir, func(after *Position) {
+			evals[dir] = eval.Evaluate(after)
+			if evals[dir] > best {
+				pickDir, best = dir, evals[dir]
 			}
+		})
+	}
+
+	if len(st.path) >= 2 {
+		next := api.Coord{X: st.path[1].X, Y: st.path[1].Y}
+		dir := me[0].Dir(next)
+		e, ok := evals[dir]
+		worth := pos == nil || decoded.You.Health <= 30 || (ok && e >= best-pyraSlack)
+
+		if decoded.Board.HasRoom(next, len(me)) && worth {
+			pickDir = dir
+			st.path = st.path[1:]
+		} else {
+			st.path = nil
 		}
-	} else {
-		pickDir = me[0].Dir(api.Coord{
-			X: st.path[1].X,
-			Y: st.path[1].Y,
+	}
+
+	p.store().PutTurn(api.KeyOf(decoded), decoded.Turn, st)
+
+	if pos != nil {
+		pos.After(pickDir, func(after *Position) {
+			ln.Log(ctx, ln.Info("evaluated"), eval.Explain(after).F())
 		})
-		st.path = st.path[1:]
 	}
 
-	p.targets[decoded.Game.ID] = st
+	// a target that was never reached by a path means none was picked
+	if st.trg != nil && st.trg.AstarLength > 0 {
+		api.Annotate(ctx, "target", fmt.Sprintf("score %d, %d away", st.trg.Score, st.trg.AstarLength), st.trg.Line.B)
+	}
+
+	if len(st.path) > 0 {
+		api.Annotate(ctx, "path", nil, st.coords()...)
+	}
 
 	return &api.MoveResponse{
 		Move: pickDir,
 	}, nil
 }
 
+func (p *Pyra) evaluator() *WeightedEval {
+	if p.eval == nil {
+		return defaultEval
+	}
+
+	return p.eval
+}
+
+func (p *Pyra) scores() PyraScores {
+	if p.Scores == (PyraScores{}) {
+		return DefaultPyraScores()
+	}
+
+	return p.Scores
+}
+
 // End ends a game.
 func (p *Pyra) End(ctx context.Context, sr api.SnakeRequest) error {
-	delete(p.targets, sr.Game.ID)
+	p.store().Delete(api.KeyOf(sr))
 
 	return nil
 }
 
-func (p Pyra) selectTarget(ctx context.Context, gs api.SnakeRequest, pf *goeasystar.Pathfinder) pyraTarget {
+// pyraDebug is what Debug shows of a pyraState.
+type pyraDebug struct {
+	Target      *api.Coord  `json:"target,omitempty"`
+	Score       int         `json:"score,omitempty"`
+	AstarLength int         `json:"astar_length,omitempty"`
+	Path        []api.Coord `json:"path"`
+}
+
+// Debug shows the target and path Pyra has for every game it is playing,
+// by game and snake ID.
+func (p *Pyra) Debug() interface{} {
+	result := map[string]pyraDebug{}
+	p.store().Each(func(key api.GameKey, v interface{}) bool {
+		st := v.(pyraState)
+
+		d := pyraDebug{Path: st.coords()}
+		if st.trg != nil {
+			target := st.trg.Line.B
+			d.Target = &target
+			d.Score, d.AstarLength = st.trg.Score, st.trg.AstarLength
+		}
+		result[key.GameID+"/"+key.SnakeID] = d
+
+		return true
+	})
+
+	return result
+}
+
+func (p *Pyra) selectTarget(ctx context.Context, gs api.SnakeRequest, pf *goeasystar.Pathfinder) pyraTarget {
 	ctx = opname.With(ctx, "select-target")
 	me := gs.You.Body
+	scores := p.scores()
 	var targets []pyraTarget
 	for _, fd := range gs.Board.Food {
 		t := pyraTarget{
@@ -126,29 +299,29 @@ func (p Pyra) selectTarget(ctx context.Context, gs api.SnakeRequest, pf *goeasys
 				A: me[0],
 				B: fd,
 			},
-			Score: 20,
+			Score: scores.Food,
 		}
 
 		if len(me) < p.MinLength {
-			t.Score = 50
+			t.Score = scores.ShortFood
 		}
 
 		if gs.You.Health <= 30 {
-			t.Score = 9000
+			t.Score = scores.HungryFood
 		}
 
 		path, err := pf.FindPath(me[0].X, me[0].Y, fd.X, fd.Y)
 		if err != nil {
 			continue
 		}
-		t.AstarLength = len(path)
+		t.AstarLength, t.first = len(path), firstStep(me[0], path)
 
 		targets = append(targets, t)
 	}
 
 	{
 		tail := me[len(me)-1]
-		for _, place := range []api.Coord{tail.Up(), tail.Down(), tail.Left(), tail.Right()} {
+		for _, place := range tail.Neighbors() {
 			path, err := pf.FindPath(me[0].X, me[0].Y, place.X, place.Y)
 			if err != nil {
 				continue
@@ -159,8 +332,9 @@ func (p Pyra) selectTarget(ctx context.Context, gs api.SnakeRequest, pf *goeasys
 					A: me[0],
 					B: tail,
 				},
-				Score:       50,
+				Score:       scores.Tail,
 				AstarLength: len(path),
+				first:       firstStep(me[0], path),
 			})
 			break
 		}
@@ -182,14 +356,15 @@ func (p Pyra) selectTarget(ctx context.Context, gs api.SnakeRequest, pf *goeasys
 				A: me[0],
 				B: head,
 			},
-			Score:       400,
+			Score:       scores.Head,
 			AstarLength: len(path),
+			first:       firstStep(me[0], path),
 		})
 	}
 
 	if len(targets) == 0 {
 		ln.Log(ctx, ln.Info("no targets found"))
-		for _, place := range []api.Coord{me[0].Up(), me[0].Down(), me[0].Left(), me[0].Right()} {
+		for _, place := range me[0].Neighbors() {
 			if !gs.Board.IsDeadly(place) {
 				return pyraTarget{
 					Line: api.Line{
@@ -201,11 +376,14 @@ func (p Pyra) selectTarget(ctx context.Context, gs api.SnakeRequest, pf *goeasys
 		}
 	}
 
+	p.weigh(gs, targets)
+
 	var t pyraTarget
 	for _, pt := range targets {
 		pt.Score = pt.Score - int(pt.Line.Manhattan())
-		for _, place := range []api.Coord{pt.Line.B.Up(), pt.Line.B.Down(), pt.Line.B.Left(), pt.Line.B.Right()} {
-			if gs.Board.IsDeadly(place) {
+		// walls don't count, or nothing on the edge would ever be a target
+		for _, place := range pt.Line.B.Neighbors() {
+			if gs.Board.Inside(place) && gs.Board.IsDeadly(place) {
 				goto next
 			}
 		}
@@ -228,3 +406,44 @@ func (p Pyra) selectTarget(ctx context.Context, gs api.SnakeRequest, pf *goeasys
 
 	return t
 }
+
+// weigh adds how good a position the first step towards each target leads
+// to to its score, so of two targets Pyra wants about as much it goes for
+// the one that leaves it more room.
+func (p *Pyra) weigh(gs api.SnakeRequest, targets []pyraTarget) {
+	s := bitboard.FromRequest(gs)
+	if s.You < 0 {
+		return
+	}
+
+	pos, eval := NewPosition(s, s.You), p.evaluator()
+	evals := map[api.Direction]int{}
+	for i := range targets {
+		t := &targets[i]
+		if !t.first.Valid() {
+			continue
+		}
+
+		e, ok := evals[t.first]
+		if !ok {
+			pos.After(t.first, func(after *Position) {
+				// moves that don't survive are left to the pathfinding
+				if after.Snakes[after.Me].Alive {
+					e = int(math.Round(eval.Evaluate(after)))
+				}
+			})
+			evals[t.first] = e
+		}
+
+		t.Score += e
+	}
+}
+
+// firstStep is the way the first step of path goes from head.
+func firstStep(head api.Coord, path []*goeasystar.Point) api.Direction {
+	if len(path) < 2 {
+		return api.NoDirection
+	}
+
+	return head.Dir(api.Coord{X: path[1].X, Y: path[1].Y})
+}
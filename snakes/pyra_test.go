@@ -0,0 +1,52 @@
+package snakes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Xe/bsnk/api"
+)
+
+func TestPyraNonSquare(t *testing.T) {
+	for _, tc := range nonSquareCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			b, _ := Lookup("pyra")
+			ai, err := b.Make(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mr, err := ai.Move(context.Background(), tc.sr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if mr.Move != tc.want {
+				t.Errorf("wanted %s towards the food on the edge, got: %s", tc.want, mr.Move)
+			}
+		})
+	}
+}
+
+func TestPyraStore(t *testing.T) {
+	p := &Pyra{}
+	p.SetName("pyra-hungry")
+	if name := p.store().Name; name != "pyra-hungry" {
+		t.Errorf("wanted the store labelled with the name Pyra is served under, got: %q", name)
+	}
+}
+
+func TestPyraWeigh(t *testing.T) {
+	p := &Pyra{}
+	if p.scores() != DefaultPyraScores() {
+		t.Errorf("wanted a zero Pyra to play with the default scores, got %+v", p.scores())
+	}
+
+	// of two targets wanted as much, the one through the pocket should
+	// end up wanted less
+	targets := []pyraTarget{{first: api.Left}, {first: api.Up}}
+	p.weigh(lookaheadCases()[0].sr, targets)
+	if targets[0].Score >= targets[1].Score {
+		t.Errorf("wanted going up to score higher, got %+v", targets)
+	}
+}
@@ -0,0 +1,133 @@
+package snakes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Xe/bsnk/api"
+)
+
+// Brain is a kind of snake that can be run, along with the settings it can
+// be tuned with. Brains register themselves when the package is loaded.
+type Brain struct {
+	Name string
+
+	// Config makes a config holding the brain's default settings, for
+	// settings from a config file to be decoded over. It is nil for brains
+	// that can't be tuned.
+	Config func() interface{}
+
+	// New makes a fresh instance of the brain with a config made by Config,
+	// or nil if Config is nil.
+	New func(config interface{}) (api.AI, error)
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]Brain{}
+)
+
+// Register makes a brain available by name. It panics if the name is
+// already taken, since that is always a programming mistake.
+func Register(b Brain) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if b.Name == "" || b.New == nil {
+		panic("snakes: Register needs a name and a New func")
+	}
+
+	if _, dup := registry[b.Name]; dup {
+		panic("snakes: Register called twice for " + b.Name)
+	}
+
+	registry[b.Name] = b
+}
+
+// Lookup finds a registered brain by name.
+func Lookup(name string) (Brain, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	b, ok := registry[name]
+	return b, ok
+}
+
+// Names lists every registered brain in order.
+func Names() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	var result []string
+	for name := range registry {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// Make makes a fresh instance of the brain. Settings, if set, is a JSON
+// object decoded over the brain's defaults; settings the brain doesn't have
+// are an error so typos don't go unnoticed.
+func (b Brain) Make(settings json.RawMessage) (api.AI, error) {
+	cfg, err := b.config(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.New(cfg)
+}
+
+// Settings is what the brain's settings come to with settings decoded over
+// its defaults, as JSON, or nil if it has none.
+func (b Brain) Settings(settings json.RawMessage) (json.RawMessage, error) {
+	cfg, err := b.config(settings)
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+
+	return json.Marshal(cfg)
+}
+
+// Defaults is the brain's default settings as JSON, or nil if it has none.
+func (b Brain) Defaults() json.RawMessage {
+	data, _ := b.Settings(nil)
+	return data
+}
+
+func (b Brain) config(settings json.RawMessage) (interface{}, error) {
+	settings = bytes.TrimSpace(settings)
+	if b.Config == nil {
+		if len(settings) != 0 && string(settings) != "null" {
+			return nil, fmt.Errorf("snakes: %s has no settings", b.Name)
+		}
+
+		return nil, nil
+	}
+
+	cfg := b.Config()
+	if len(settings) != 0 {
+		dec := json.NewDecoder(bytes.NewReader(settings))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("snakes: settings for %s: %w", b.Name, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// stateless registers a brain with no settings or state, so one instance
+// does for every game.
+func stateless(name string, ai api.AI) {
+	Register(Brain{
+		Name: name,
+		New: func(interface{}) (api.AI, error) {
+			return ai, nil
+		},
+	})
+}
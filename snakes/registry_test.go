@@ -0,0 +1,47 @@
+package snakes
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{"ahri", "erratic", "garen", "greedy", "pyra", "sunset"} {
+		if _, ok := Lookup(name); !ok {
+			t.Errorf("%s isn't registered", name)
+		}
+	}
+
+	b, _ := Lookup("pyra")
+	ai, err := b.Make(json.RawMessage(`{"min_length": 20}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := ai.(*Pyra); p.MinLength != 20 {
+		t.Errorf("wanted min_length 20, got: %d", p.MinLength)
+	}
+
+	ai, err = b.Make(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := ai.(*Pyra); p.MinLength != 8 {
+		t.Errorf("wanted the default min_length of 8, got: %d", p.MinLength)
+	}
+
+	for _, tc := range []struct {
+		brain, settings string
+	}{
+		{brain: "pyra", settings: `{"min_lenght": 20}`},
+		{brain: "pyra", settings: `{"min_length": -1}`},
+		{brain: "garen", settings: `{"speed": 2}`},
+		{brain: "ahri", settings: `{"weights": {"helth": 1}}`},
+	} {
+		b, _ := Lookup(tc.brain)
+		if _, err := b.Make(json.RawMessage(tc.settings)); err == nil {
+			t.Errorf("%s should have refused %s", tc.brain, tc.settings)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package snakes
+
+import (
+	"math/rand"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/bitboard"
+)
+
+// RolloutPolicy picks a move for snake i when playing a game out quickly to
+// see how it ends. moves are the snake's safe moves, of which there is at
+// least one. It must be cheap: it runs for every snake on every turn of
+// every playout.
+type RolloutPolicy func(s *bitboard.State, i int, moves []api.Direction, rng *rand.Rand) api.Direction
+
+// RolloutPolicies are the playout policies search brains can be configured
+// with, by name.
+var RolloutPolicies = map[string]RolloutPolicy{
+	"random": RandomRollout,
+	"greedy": GreedyRollout,
+	"pyra":   PyraRollout,
+}
+
+// RandomRollout picks any safe move.
+func RandomRollout(s *bitboard.State, i int, moves []api.Direction, rng *rand.Rand) api.Direction {
+	return moves[rng.Intn(len(moves))]
+}
+
+// GreedyRollout heads for the closest food, like Greedy.
+func GreedyRollout(s *bitboard.State, i int, moves []api.Direction, rng *rand.Rand) api.Direction {
+	head := s.Coord(s.Snakes[i].Head())
+	if target, ok := closestFood(s, head); ok {
+		return towards(s, i, moves, target, rng)
+	}
+
+	return RandomRollout(s, i, moves, rng)
+}
+
+// PyraRollout picks targets roughly like Pyra: food when it is hungry or
+// short, otherwise the head of a shorter snake. It stays out of reach of
+// heads that would win a head-to-head.
+func PyraRollout(s *bitboard.State, i int, moves []api.Direction, rng *rand.Rand) api.Direction {
+	sn := &s.Snakes[i]
+	head := s.Coord(sn.Head())
+
+	// drop moves next to a head at least as long, unless that is all
+	// there is
+	var buf [4]api.Direction
+	safe := buf[:0]
+	for _, m := range moves {
+		if !threatened(s, i, s.Neighbors[sn.Head()][m-1]) {
+			safe = append(safe, m)
+		}
+	}
+	if len(safe) > 0 {
+		moves = safe
+	}
+
+	if sn.Health <= 30 || sn.Length < 8 {
+		if target, ok := closestFood(s, head); ok {
+			return towards(s, i, moves, target, rng)
+		}
+	}
+
+	best, found := 0, false
+	var target api.Coord
+	for j := range s.Snakes {
+		other := &s.Snakes[j]
+		if j == i || !other.Alive || other.Head() < 0 || other.Length >= sn.Length {
+			continue
+		}
+
+		c := s.Coord(other.Head())
+		if d := manhattan(head, c); !found || d < best {
+			best, target, found = d, c, true
+		}
+	}
+	if found {
+		return towards(s, i, moves, target, rng)
+	}
+
+	return RandomRollout(s, i, moves, rng)
+}
+
+// threatened reports whether a snake at least as long as snake i could
+// move into square c next turn.
+func threatened(s *bitboard.State, i, c int) bool {
+	for j := range s.Snakes {
+		other := &s.Snakes[j]
+		if j == i || !other.Alive || other.Head() < 0 || other.Length < s.Snakes[i].Length {
+			continue
+		}
+
+		for _, n := range s.Neighbors[other.Head()] {
+			if n == c {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// closestFood finds the food closest to c as the crow flies.
+func closestFood(s *bitboard.State, c api.Coord) (api.Coord, bool) {
+	var (
+		target api.Coord
+		best   int
+		found  bool
+	)
+
+	s.Food.Each(func(f int) {
+		fc := s.Coord(f)
+		if d := manhattan(c, fc); !found || d < best {
+			best, target, found = d, fc, true
+		}
+	})
+
+	return target, found
+}
+
+// towards picks the move that gets snake i closest to target, breaking
+// ties at random.
+func towards(s *bitboard.State, i int, moves []api.Direction, target api.Coord, rng *rand.Rand) api.Direction {
+	head := s.Snakes[i].Head()
+	best, bestDist, ties := moves[0], 1<<30, 0
+	for _, m := range moves {
+		d := manhattan(s.Coord(s.Neighbors[head][m-1]), target)
+		switch {
+		case d < bestDist:
+			best, bestDist, ties = m, d, 1
+		case d == bestDist:
+			ties++
+			if rng.Intn(ties) == 0 {
+				best = m
+			}
+		}
+	}
+
+	return best
+}
+
+func manhattan(a, b api.Coord) int {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+
+	return dx + dy
+}
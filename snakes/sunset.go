@@ -3,7 +3,6 @@ package snakes
 import (
 	"container/heap"
 	"context"
-	"math"
 
 	"github.com/Xe/bsnk/api"
 	"within.website/ln"
@@ -12,6 +11,10 @@ import (
 // Sunset is a snake AI based off of the rantings of Ahroo in Discord DM.
 type Sunset struct{}
 
+func init() {
+	stateless("sunset", Sunset{})
+}
+
 func (Sunset) Ping() (*api.PingResponse, error) {
 	return &api.PingResponse{
 		APIVersion: "1",
@@ -128,31 +131,21 @@ func (Sunset) Move(ctx context.Context, decoded api.SnakeRequest) (*api.MoveResp
 
 	trueTargetNode := BestNode
 
-	for trueTargetNode.Previous != -1 {
+	// walk back to the first step of the path, which is the node whose
+	// parent is our head at NodePool[0]
+	for trueTargetNode.Previous > 0 {
 		trueTargetNode = &NodePool[trueTargetNode.Previous]
 	}
 
-	var pickDir string
-
 	ctx = ln.WithF(ctx, logCoords("target", target))
 	ctx = ln.WithF(ctx, logCoords("trueTarget", trueTargetNode.Node))
 	ctx = ln.WithF(ctx, logCoords("bestNode", BestNode.Node))
 	ctx = ln.WithF(ctx, logCoords("my_head", me[0]))
 
-	diff := api.Coord{trueTargetNode.Node.X - me[0].X, trueTargetNode.Node.Y - me[0].Y}
-
-	if math.Abs(float64(diff.X)) > math.Abs(float64(diff.Y)) {
-		if diff.X > 0 {
-			pickDir = "right"
-		} else {
-			pickDir = "left"
-		}
-	} else {
-		if diff.Y > 0 {
-			pickDir = "down"
-		} else {
-			pickDir = "up"
-		}
+	pickDir := me[0].Dir(trueTargetNode.Node)
+	if !pickDir.Valid() {
+		// no path out of here, take the move least likely to kill us
+		pickDir = api.SafestMove(decoded)
 	}
 
 	return &api.MoveResponse{
@@ -206,15 +199,7 @@ func sunsetHeuristic(from, to api.Coord) float64 {
 func sunsetGetNeighbors(focus api.Coord, board api.Board) []api.Coord {
 	var result []api.Coord
 
-	offsets := []api.Coord{
-		api.Coord{1, 0},
-		api.Coord{0, 1},
-		api.Coord{-1, 0},
-		api.Coord{0, -1},
-	}
-
-	for _, currOffs := range offsets {
-		newCoord := api.Coord{focus.X + currOffs.X, focus.Y + currOffs.Y}
+	for _, newCoord := range focus.Neighbors() {
 		if board.Inside(newCoord) {
 			safe := true
 
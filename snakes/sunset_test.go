@@ -0,0 +1,34 @@
+package snakes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Xe/bsnk/api"
+)
+
+func TestSunsetNonSquare(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		food api.Coord
+		want api.Direction
+	}{
+		{name: "right", food: api.Coord{X: 8, Y: 2}, want: api.Right},
+		{name: "up", food: api.Coord{X: 2, Y: 4}, want: api.Up},
+		{name: "down", food: api.Coord{X: 2, Y: 0}, want: api.Down},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sr := wideRequest()
+			sr.Board.Food = []api.Coord{tc.food}
+
+			mr, err := Sunset{}.Move(context.Background(), sr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if mr.Move != tc.want {
+				t.Errorf("wanted %s towards %s, got: %s", tc.want, tc.food, mr.Move)
+			}
+		})
+	}
+}
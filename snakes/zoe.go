@@ -0,0 +1,506 @@
+package snakes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/bitboard"
+	"within.website/ln"
+)
+
+// Zoe plays out thousands of quick games from the position and goes where
+// they turn out best: Monte Carlo tree search. Snakes move at the same time,
+// so at every node of the tree each snake picks its move separately, by
+// UCB1 over how its own moves have done there (decoupled UCT). It uses every
+// core until the move deadline, and keeps the part of the tree the game went
+// down for the next turn.
+//
+// Struct members are configuration flags for the snake behavior.
+type Zoe struct {
+	// Exploration is how much Zoe tries moves that haven't been played out
+	// much over the ones that have done best so far.
+	Exploration float64 `json:"exploration"`
+
+	// Rollout is how games are played out past the tree: "random",
+	// "greedy" or "pyra" (see RolloutPolicies).
+	Rollout string `json:"rollout"`
+
+	// RolloutDepth is how many turns games are played out for past the
+	// tree before they are scored.
+	RolloutDepth int `json:"rollout_depth"`
+
+	// Workers is how many games are played out at once. If zero, one per
+	// core.
+	Workers int `json:"workers"`
+
+	// Playouts is the most games played out per move, if not zero. Without
+	// a move deadline it is the only limit; otherwise Zoe stops after
+	// api.DefaultTimeout.
+	Playouts int `json:"playouts"`
+
+	// Seed seeds how games are played out, if not zero. With one worker and
+	// no deadline, a search with the same seed plays out the same games.
+	Seed int64 `json:"seed"`
+
+	// gameStates holds a zoeTree for every game being played.
+	gameStates
+}
+
+func init() {
+	Register(Brain{
+		Name: "zoe",
+		Config: func() interface{} {
+			return &Zoe{Exploration: 0.7, Rollout: "pyra", RolloutDepth: 20}
+		},
+		New: func(config interface{}) (api.AI, error) {
+			z := config.(*Zoe)
+			switch {
+			case z.Exploration < 0:
+				return nil, fmt.Errorf("zoe: exploration must not be negative, got %g", z.Exploration)
+			case RolloutPolicies[z.Rollout] == nil:
+				return nil, fmt.Errorf("zoe: unknown rollout %q", z.Rollout)
+			case z.RolloutDepth < 0:
+				return nil, fmt.Errorf("zoe: rollout_depth must not be negative, got %d", z.RolloutDepth)
+			case z.Workers < 0:
+				return nil, fmt.Errorf("zoe: workers must not be negative, got %d", z.Workers)
+			case z.Playouts < 0:
+				return nil, fmt.Errorf("zoe: playouts must not be negative, got %d", z.Playouts)
+			}
+
+			return z, nil
+		},
+	})
+}
+
+func (*Zoe) Ping() (*api.PingResponse, error) {
+	return &api.PingResponse{
+		APIVersion: "1",
+		Color:      "#f29e4c",
+		HeadType:   "silly",
+		TailType:   "curled",
+	}, nil
+}
+
+func (z *Zoe) store() *api.StateStore {
+	return z.states("zoe")
+}
+
+// Start starts a game.
+func (z *Zoe) Start(ctx context.Context, sr api.SnakeRequest) error {
+	return nil
+}
+
+// zoeTree is the tree kept between turns of a game, along with what it
+// takes to find where the game went in it.
+type zoeTree struct {
+	root  *zoeNode
+	turn  int
+	ids   []string
+	heads []int
+	last  zoeResult
+}
+
+// zoeResult is what the last search of a game found.
+type zoeResult struct {
+	Move     api.Direction  `json:"move"`
+	Value    float64        `json:"value"`
+	Playouts int            `json:"playouts"`
+	Reused   int            `json:"reused"`
+	Visits   map[string]int `json:"visits"`
+}
+
+// advance finds the node for s, the turn after the tree's root, if the
+// tree has it. It only does if nobody died in between.
+func (t zoeTree) advance(s *bitboard.State) *zoeNode {
+	if t.root == nil || s.Turn != t.turn+1 || len(s.Snakes) != len(t.ids) {
+		return nil
+	}
+
+	var key uint64
+	for i := range s.Snakes {
+		sn := &s.Snakes[i]
+		if sn.ID != t.ids[i] || !sn.Alive || t.heads[i] < 0 {
+			return nil
+		}
+
+		dir := api.NoDirection
+		for d, n := range s.Neighbors[t.heads[i]] {
+			if n >= 0 && n == sn.Head() {
+				dir = api.Direction(d + 1)
+			}
+		}
+		if dir == api.NoDirection {
+			return nil
+		}
+
+		key |= zoeKey(i, dir)
+	}
+
+	return t.root.children[key]
+}
+
+// zoeMaxSnakes is how many snakes a node's key has room for. Zoe doesn't
+// search games with more than that.
+const zoeMaxSnakes = 21
+
+// zoeKey is the part of a node's key for snake i moving dir.
+func zoeKey(i int, dir api.Direction) uint64 {
+	return uint64(dir) << (3 * uint(i))
+}
+
+// Move responds with the snake's movements for a given Turn.
+func (z *Zoe) Move(ctx context.Context, sr api.SnakeRequest) (*api.MoveResponse, error) {
+	s := bitboard.FromRequest(sr)
+	if s.You < 0 {
+		return &api.MoveResponse{Move: api.SafestMove(sr)}, nil
+	}
+
+	var buf [4]api.Direction
+	if moves := s.Moves(s.You, buf[:0]); len(moves) < 2 || len(s.Snakes) > zoeMaxSnakes {
+		// nothing to think about, or too many snakes to tell joint moves
+		// apart
+		move := api.SafestMove(sr)
+		if len(moves) == 1 {
+			move = moves[0]
+		}
+
+		return &api.MoveResponse{Move: move}, nil
+	}
+
+	if _, timed := ctx.Deadline(); !timed && z.Playouts == 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, api.DefaultTimeout)
+		defer cancel()
+	}
+	ctx, cancel := api.Trim(ctx)
+	defer cancel()
+
+	v, _ := z.store().Get(api.KeyOf(sr))
+	tree, _ := v.(zoeTree)
+	root := tree.advance(s)
+	if root == nil {
+		root = &zoeNode{}
+	}
+	reused := root.visits
+
+	t := &zoeSearch{
+		root:  root,
+		me:    s.You,
+		multi: s.Alive() > 1,
+		c:     z.Exploration,
+		depth: z.RolloutDepth,
+		roll:  RolloutPolicies[z.Rollout],
+	}
+	if t.roll == nil {
+		t.roll = RandomRollout
+	}
+
+	workers := z.Workers
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		started  int64
+		playouts int64
+		seed     = z.Seed
+	)
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	for k := 0; k < workers; k++ {
+		w := t.worker(s.Clone(), seed+int64(k))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// always play out at least one game, so there is a move to
+			// pick even if the deadline is already close
+			for n := 0; ; n++ {
+				if n > 0 && api.Late(ctx) {
+					return
+				}
+				if z.Playouts > 0 && atomic.AddInt64(&started, 1) > int64(z.Playouts) {
+					return
+				}
+
+				w.iterate()
+				atomic.AddInt64(&playouts, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := t.result()
+	result.Playouts, result.Reused = int(playouts), reused
+
+	ids := make([]string, len(s.Snakes))
+	heads := make([]int, len(s.Snakes))
+	for i := range s.Snakes {
+		ids[i], heads[i] = s.Snakes[i].ID, s.Snakes[i].Head()
+	}
+	z.store().PutTurn(api.KeyOf(sr), sr.Turn, zoeTree{root: root, turn: s.Turn, ids: ids, heads: heads, last: result})
+
+	ln.Log(ctx, ln.Info("searched"), ln.F{
+		"search_playouts": result.Playouts,
+		"search_reused":   result.Reused,
+		"search_value":    result.Value,
+		"search_move":     result.Move,
+	})
+	api.Annotate(ctx, "search", fmt.Sprintf("%d playouts (%d reused), value %.2f", result.Playouts, result.Reused, result.Value))
+
+	return &api.MoveResponse{Move: result.Move}, nil
+}
+
+// End ends a game.
+func (z *Zoe) End(ctx context.Context, sr api.SnakeRequest) error {
+	z.store().Delete(api.KeyOf(sr))
+
+	return nil
+}
+
+// Debug shows what the last search found in every game Zoe is playing, by
+// game and snake ID.
+func (z *Zoe) Debug() interface{} {
+	result := map[string]zoeResult{}
+	z.store().Each(func(key api.GameKey, v interface{}) bool {
+		result[key.GameID+"/"+key.SnakeID] = v.(zoeTree).last
+		return true
+	})
+
+	return result
+}
+
+// zoeNode is a position in the tree. Its children are the positions every
+// combination of moves leads to, by zoeKey.
+//
+// Each node has its own lock, held only while a worker picks moves there or
+// scores them, so workers only wait on each other when they are at the same
+// node at the same time.
+type zoeNode struct {
+	lock   sync.Mutex
+	visits int
+
+	// moves are, by snake, the moves each can make, and arms how each of
+	// those has done. They are nil until the node is first visited, and
+	// for snakes that are dead.
+	moves [][]api.Direction
+	arms  [][]zoeArm
+
+	// over is whether the game is over for us here.
+	over bool
+
+	children map[uint64]*zoeNode
+}
+
+// zoeArm is how one move of one snake has done at a node. Visits count as
+// soon as the move is picked, before its playout is scored, so workers
+// playing at the same time spread out over the tree.
+type zoeArm struct {
+	visits int
+	reward float64
+}
+
+// zoeSearch is one search of one position by a pool of workers.
+type zoeSearch struct {
+	root  *zoeNode
+	me    int
+	multi bool
+	c     float64
+	depth int
+	roll  RolloutPolicy
+}
+
+// over reports whether the game is over for us in s.
+func (t *zoeSearch) over(s *bitboard.State) bool {
+	return !s.Snakes[t.me].Alive || t.multi && s.Alive() < 2
+}
+
+// expand works out the moves at n, which is locked, for the position s.
+func (t *zoeSearch) expand(n *zoeNode, s *bitboard.State) {
+	n.moves = make([][]api.Direction, len(s.Snakes))
+	n.arms = make([][]zoeArm, len(s.Snakes))
+	n.over = t.over(s)
+	if n.over {
+		return
+	}
+
+	for i := range s.Snakes {
+		if !s.Snakes[i].Alive {
+			continue
+		}
+
+		n.moves[i] = s.Moves(i, nil)
+		if len(n.moves[i]) == 0 {
+			// nowhere to go, so it makes no difference
+			n.moves[i] = []api.Direction{api.NoDirection}
+		}
+		n.arms[i] = make([]zoeArm, len(n.moves[i]))
+	}
+}
+
+// choose picks a move for snake i at n by UCB1, trying every move once
+// first.
+func (t *zoeSearch) choose(n *zoeNode, i int, rng *rand.Rand) int {
+	arms := n.arms[i]
+	start := rng.Intn(len(arms))
+	for j := range arms {
+		if k := (start + j) % len(arms); arms[k].visits == 0 {
+			return k
+		}
+	}
+
+	logN := math.Log(float64(n.visits))
+	best, bestScore := 0, math.Inf(-1)
+	for k, a := range arms {
+		v := float64(a.visits)
+		if score := a.reward/v + t.c*math.Sqrt(logN/v); score > bestScore {
+			best, bestScore = k, score
+		}
+	}
+
+	return best
+}
+
+// result is our most played move at the root.
+func (t *zoeSearch) result() zoeResult {
+	root := t.root
+	root.lock.Lock()
+	defer root.lock.Unlock()
+
+	r := zoeResult{Move: api.Up, Visits: map[string]int{}}
+	if root.moves == nil || root.over {
+		return r
+	}
+
+	most := -1
+	for k, a := range root.arms[t.me] {
+		m := root.moves[t.me][k]
+		r.Visits[m.String()] = a.visits
+		if a.visits > most && m.Valid() {
+			most, r.Move = a.visits, m
+			r.Value = a.reward / math.Max(float64(a.visits), 1)
+		}
+	}
+
+	return r
+}
+
+// zoeWorker plays games out on its own copy of the position.
+type zoeWorker struct {
+	t   *zoeSearch
+	s   *bitboard.State
+	rng *rand.Rand
+
+	// path is the nodes the last playout went through, and picks the move
+	// each snake picked at each of them, or -1.
+	path    []*zoeNode
+	picks   []int
+	joint   []api.Direction
+	rewards []float64
+}
+
+func (t *zoeSearch) worker(s *bitboard.State, seed int64) *zoeWorker {
+	return &zoeWorker{
+		t:       t,
+		s:       s,
+		rng:     rand.New(rand.NewSource(seed)),
+		joint:   make([]api.Direction, len(s.Snakes)),
+		rewards: make([]float64, len(s.Snakes)),
+	}
+}
+
+// iterate walks down the tree to a new node, plays the game out from there
+// and tells every node on the way how it went. Moves are made on the
+// worker's own copy of the position, with no node locked.
+func (w *zoeWorker) iterate() {
+	t, s := w.t, w.s
+	made := 0
+
+	w.path, w.picks = w.path[:0], w.picks[:0]
+	for n := t.root; n != nil; {
+		n.lock.Lock()
+		if n.moves == nil {
+			t.expand(n, s)
+		}
+		if n.over {
+			n.lock.Unlock()
+			break
+		}
+
+		n.visits++
+		w.path = append(w.path, n)
+
+		var key uint64
+		for i := range n.moves {
+			pick := -1
+			w.joint[i] = api.NoDirection
+			if n.moves[i] != nil {
+				pick = t.choose(n, i, w.rng)
+				n.arms[i][pick].visits++
+				w.joint[i] = n.moves[i][pick]
+				key |= zoeKey(i, w.joint[i])
+			}
+			w.picks = append(w.picks, pick)
+		}
+
+		// the first worker to get somewhere new adds it and plays it out
+		child, ok := n.children[key]
+		if !ok {
+			if n.children == nil {
+				n.children = map[uint64]*zoeNode{}
+			}
+			n.children[key] = &zoeNode{}
+		}
+		n.lock.Unlock()
+
+		s.Make(w.joint)
+		made++
+		n = child
+	}
+
+	var buf [4]api.Direction
+	for d := 0; d < t.depth && !t.over(s); d++ {
+		for i := range s.Snakes {
+			w.joint[i] = api.NoDirection
+			if moves := s.Moves(i, buf[:0]); len(moves) > 0 {
+				w.joint[i] = t.roll(s, i, moves, w.rng)
+			}
+		}
+
+		s.Make(w.joint)
+		made++
+	}
+
+	// the survivors share the win
+	alive := s.Alive()
+	for i := range s.Snakes {
+		w.rewards[i] = 0
+		if s.Snakes[i].Alive {
+			w.rewards[i] = 1 / float64(alive)
+		}
+	}
+
+	for ; made > 0; made-- {
+		s.Unmake()
+	}
+
+	for k, n := range w.path {
+		n.lock.Lock()
+		for i := range n.moves {
+			if pick := w.picks[k*len(n.moves)+i]; pick >= 0 {
+				n.arms[i][pick].reward += w.rewards[i]
+			}
+		}
+		n.lock.Unlock()
+	}
+}
@@ -0,0 +1,130 @@
+package snakes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+)
+
+func TestZoe(t *testing.T) {
+	for _, tc := range lookaheadCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			// one seeded worker with no deadline plays out the same games
+			// every time, however busy the machine is
+			b, _ := Lookup("zoe")
+			ai, err := b.Make(json.RawMessage(`{"workers": 1, "seed": 1, "playouts": 3000}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mr, err := ai.Move(context.Background(), tc.sr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			last := ai.(*Zoe).Debug().(map[string]zoeResult)[tc.sr.Game.ID+"/me"]
+			if mr.Move != tc.want || last.Playouts != 3000 {
+				t.Errorf("wanted %s after 3000 playouts, got %s after %+v", tc.want, mr.Move, last)
+			}
+
+			// the moves that lose should be played out clearly less
+			for move, visits := range last.Visits {
+				if move != tc.want.String() && visits*3 > last.Visits[tc.want.String()]*2 {
+					t.Errorf("%s should have clearly fewer visits than %s, got %v", move, tc.want, last.Visits)
+				}
+			}
+		})
+	}
+
+	// the tree is kept for the next turn if the game goes somewhere in it
+	z := &Zoe{Exploration: 0.7, Rollout: "random", RolloutDepth: 10, Workers: 1, Playouts: 2000, Seed: 1}
+	sr := lookaheadCases()[0].sr
+	if _, err := z.Move(context.Background(), sr); err != nil {
+		t.Fatal(err)
+	}
+
+	sr.Turn++
+	for i := range sr.Board.Snakes {
+		sn := &sr.Board.Snakes[i]
+		sn.Body = append([]api.Coord{sn.Body[0].Up()}, sn.Body[:len(sn.Body)-1]...)
+	}
+	sr.You = sr.Board.Snakes[0]
+	sr.Normalize()
+
+	if _, err := z.Move(context.Background(), sr); err != nil {
+		t.Fatal(err)
+	}
+
+	if last := z.Debug().(map[string]zoeResult)["pocket/me"]; last.Reused == 0 {
+		t.Errorf("wanted the tree reused, got %+v", last)
+	}
+}
+
+func TestZoeCrowded(t *testing.T) {
+	me := api.Snake{
+		ID:     "me",
+		Health: 90,
+		Body:   []api.Coord{{X: 5, Y: 5}, {X: 5, Y: 4}, {X: 5, Y: 3}},
+	}
+
+	sr := api.SnakeRequest{
+		Game:  api.Game{ID: "crowded"},
+		Board: api.Board{Width: 11, Height: 11, Snakes: []api.Snake{me}},
+		You:   me,
+	}
+	for x := 0; x < 11; x++ {
+		for _, y := range []int{0, 10} {
+			sr.Board.Snakes = append(sr.Board.Snakes, api.Snake{
+				ID:     fmt.Sprintf("%d-%d", x, y),
+				Health: 90,
+				Body:   []api.Coord{{X: x, Y: y}},
+			})
+		}
+	}
+	sr.Normalize()
+
+	// more snakes than a key has room for would mix up joint moves, so
+	// there is no search
+	z := &Zoe{Exploration: 0.7, Rollout: "random", RolloutDepth: 10, Workers: 1, Playouts: 100, Seed: 1}
+	mr, err := z.Move(context.Background(), sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := api.SafestMove(sr); mr.Move != want {
+		t.Errorf("wanted %s, got %s", want, mr.Move)
+	}
+
+	if _, ok := z.Debug().(map[string]zoeResult)["crowded/me"]; ok {
+		t.Error("wanted no search with 23 snakes")
+	}
+}
+
+// BenchmarkZoe reports how many games Zoe plays out in a 100ms move with
+// more and more workers. With a core per worker it should go up about as
+// fast as the workers do.
+func BenchmarkZoe(b *testing.B) {
+	sr := lookaheadCases()[0].sr
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			playouts := 0
+			for n := 0; n < b.N; n++ {
+				z := &Zoe{Exploration: 0.7, Rollout: "pyra", RolloutDepth: 20, Workers: workers}
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				_, err := z.Move(ctx, sr)
+				cancel()
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				playouts += z.Debug().(map[string]zoeResult)["pocket/me"].Playouts
+			}
+
+			b.ReportMetric(float64(playouts)/float64(b.N), "playouts/op")
+		})
+	}
+}
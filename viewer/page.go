@@ -0,0 +1,195 @@
+package viewer
+
+// page is the whole viewer. It is kept inline so the binary serves it with
+// nothing else on disk or on the network.
+const page = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>bsnk games</title>
+<style>
+body { background: #1e1e24; color: #ddd; font: 14px/1.4 monospace; margin: 0; display: flex; height: 100vh; }
+a { color: #5ce8c3; }
+#games { width: 26em; overflow-y: auto; border-right: 1px solid #333; }
+#games h1 { font-size: 1.1em; margin: 0.8em; }
+#games .game { padding: 0.4em 0.8em; cursor: pointer; border-top: 1px solid #2a2a32; }
+#games .game:hover, #games .game.open { background: #2a2a32; }
+#games .meta { color: #888; font-size: 0.9em; }
+#games .local { color: #f58231; }
+#main { flex: 1; padding: 1em; overflow-y: auto; }
+#board svg { max-width: 100%; height: auto; max-height: 70vh; }
+#controls { margin: 0.5em 0; display: flex; gap: 0.5em; align-items: center; }
+#controls input[type=range] { flex: 1; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+td, th { padding: 0.1em 0.8em 0.1em 0; text-align: left; }
+.you { color: #5ce8c3; font-weight: bold; }
+.error { color: #ff5c5c; }
+.swatch { display: inline-block; width: 0.8em; height: 0.8em; margin-right: 0.4em; }
+#notes li { margin: 0.2em 0; }
+.empty { color: #888; margin: 0.8em; }
+</style>
+</head>
+<body>
+<div id="games"><h1>games</h1><div id="list" class="empty">loading...</div></div>
+<div id="main">
+  <p class="empty" id="hint">Pick a game on the left. Use the arrow keys to step through turns.</p>
+  <div id="viewer" hidden>
+    <div id="title"></div>
+    <div id="controls">
+      <button id="first">|&lt;</button>
+      <button id="prev">&lt;</button>
+      <button id="play">play</button>
+      <button id="next">&gt;</button>
+      <button id="last">&gt;|</button>
+      <input id="scrub" type="range" min="0" value="0">
+      <span id="turn"></span>
+    </div>
+    <div id="board"></div>
+    <div id="move"></div>
+    <table id="snakes"></table>
+    <ul id="notes"></ul>
+  </div>
+</div>
+<script>
+"use strict";
+
+var game = null, index = 0, timer = null;
+
+function $(id) { return document.getElementById(id); }
+
+function el(tag, text, cls) {
+  var e = document.createElement(tag);
+  if (text !== undefined) e.textContent = text;
+  if (cls) e.className = cls;
+  return e;
+}
+
+function loadList() {
+  fetch("games").then(function (r) { return r.json(); }).then(function (games) {
+    var list = $("list");
+    list.textContent = "";
+    list.className = "";
+    if (games.length === 0) {
+      list.className = "empty";
+      list.textContent = "No recordings yet. Start the server with -record-dir, or play games locally with it.";
+      return;
+    }
+
+    games.forEach(function (g) {
+      var row = el("div", undefined, "game");
+      row.appendChild(el("div", g.snake + " in " + g.game_id));
+      var meta = el("div", g.ruleset + " " + g.width + "x" + g.height + " vs " + (g.snakes || []).join(", "), "meta");
+      if (g.source === "local") meta.appendChild(el("span", " (local)", "local"));
+      row.appendChild(meta);
+      row.appendChild(el("div", new Date(g.modified).toLocaleString(), "meta"));
+      row.onclick = function () {
+        Array.prototype.forEach.call(document.querySelectorAll(".game.open"), function (o) { o.classList.remove("open"); });
+        row.classList.add("open");
+        location.hash = g.path;
+        loadGame(g.path);
+      };
+      list.appendChild(row);
+    });
+  });
+}
+
+function loadGame(path) {
+  stop();
+  fetch("games/" + path).then(function (r) {
+    if (!r.ok) throw new Error(r.statusText);
+    return r.json();
+  }).then(function (g) {
+    game = g;
+    $("hint").hidden = true;
+    $("viewer").hidden = false;
+    $("title").textContent = g.snake + ": " + g.path;
+    $("scrub").max = g.turns.length - 1;
+    show(0);
+  }).catch(function (err) {
+    $("hint").hidden = false;
+    $("hint").textContent = "can't load " + path + ": " + err.message;
+  });
+}
+
+function show(i) {
+  if (!game) return;
+  index = Math.max(0, Math.min(i, game.turns.length - 1));
+  var t = game.turns[index];
+
+  $("scrub").value = index;
+  $("turn").textContent = "turn " + t.turn + " (" + (index + 1) + "/" + game.turns.length + ")";
+  $("board").innerHTML = t.svg;
+
+  var move = $("move");
+  move.textContent = "";
+  if (t.move) move.appendChild(el("span", "moved " + t.move + " in " + (t.latency / 1e6).toFixed(1) + "ms"));
+  if (t.shout) move.appendChild(el("span", " shouting \"" + t.shout + "\""));
+  if (t.error) move.appendChild(el("span", " error: " + t.error, "error"));
+
+  var snakes = $("snakes");
+  snakes.textContent = "";
+  var head = el("tr");
+  ["snake", "health", "length"].forEach(function (h) { head.appendChild(el("th", h)); });
+  snakes.appendChild(head);
+  (t.board.snakes || []).forEach(function (sn) {
+    var row = el("tr", undefined, sn.id === game.you ? "you" : "");
+    var name = el("td");
+    var swatch = el("span", undefined, "swatch");
+    swatch.style.background = game.colors[sn.id] || "#888";
+    name.appendChild(swatch);
+    name.appendChild(document.createTextNode(sn.name || sn.id));
+    row.appendChild(name);
+    row.appendChild(el("td", String(sn.health)));
+    row.appendChild(el("td", String(sn.body.length)));
+    snakes.appendChild(row);
+  });
+
+  var notes = $("notes");
+  notes.textContent = "";
+  (t.annotations || []).forEach(function (an) {
+    var text = an.key;
+    if (an.value) text += ": " + an.value;
+    if (an.cells && an.cells.length) {
+      text += " at " + an.cells.map(function (c) { return "(" + c.x + "," + c.y + ")"; }).join(" ");
+    }
+    notes.appendChild(el("li", text));
+  });
+}
+
+function stop() {
+  if (timer) clearInterval(timer);
+  timer = null;
+  $("play").textContent = "play";
+}
+
+function play() {
+  if (timer) return stop();
+  if (game && index === game.turns.length - 1) show(0);
+  $("play").textContent = "pause";
+  timer = setInterval(function () {
+    if (!game || index >= game.turns.length - 1) return stop();
+    show(index + 1);
+  }, 150);
+}
+
+$("first").onclick = function () { show(0); };
+$("prev").onclick = function () { show(index - 1); };
+$("next").onclick = function () { show(index + 1); };
+$("last").onclick = function () { show(game ? game.turns.length - 1 : 0); };
+$("play").onclick = play;
+$("scrub").oninput = function () { show(Number(this.value)); };
+
+document.addEventListener("keydown", function (e) {
+  switch (e.key) {
+  case "ArrowLeft": show(index - 1); break;
+  case "ArrowRight": show(index + 1); break;
+  case " ": play(); e.preventDefault(); break;
+  }
+});
+
+loadList();
+if (location.hash.length > 1) loadGame(location.hash.slice(1));
+</script>
+</body>
+</html>
+`
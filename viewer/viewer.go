@@ -0,0 +1,256 @@
+// Package viewer is a web page for looking through recorded games turn by
+// turn, along with everything the brains noted while playing them.
+//
+// Games recorded by the server and games played locally with a recorder
+// both end up in the same directory of api.Recorder recordings, so the
+// viewer shows them side by side.
+package viewer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Xe/bsnk/api"
+	"github.com/Xe/bsnk/render"
+	"within.website/ln"
+)
+
+// Handler serves the viewer page and the JSON it is built from:
+//
+//	/              the viewer
+//	/games         every recording, newest first, as a list of Summary
+//	/games/<path>  one recording, as a Game
+type Handler struct {
+	// Dir is where recordings are kept, as in api.Recorder. If empty, there
+	// are no games to show.
+	Dir string
+
+	// Brains are asked for their colors, by the name recordings are made
+	// under.
+	Brains api.Brains
+}
+
+// Summary describes a recording without reading all of it.
+type Summary struct {
+	Path     string    `json:"path"`
+	Snake    string    `json:"snake"`
+	GameID   string    `json:"game_id"`
+	Source   string    `json:"source"`
+	Ruleset  string    `json:"ruleset"`
+	Width    int       `json:"width"`
+	Height   int       `json:"height"`
+	Snakes   []string  `json:"snakes"`
+	Modified time.Time `json:"modified"`
+}
+
+// Game is a whole recording, one entry per turn.
+type Game struct {
+	Path  string `json:"path"`
+	Snake string `json:"snake"`
+	You   string `json:"you"`
+
+	// Colors are the colors snakes are drawn in, by ID.
+	Colors map[string]string `json:"colors"`
+	Turns  []Turn            `json:"turns"`
+}
+
+// Turn is everything that happened on one turn of a recording.
+type Turn struct {
+	Turn  int       `json:"turn"`
+	Board api.Board `json:"board"`
+
+	// Move is empty when the turn wasn't a move request, or the brain
+	// failed to answer.
+	Move        string           `json:"move,omitempty"`
+	Shout       string           `json:"shout,omitempty"`
+	Latency     time.Duration    `json:"latency"`
+	Error       string           `json:"error,omitempty"`
+	Annotations []api.Annotation `json:"annotations,omitempty"`
+
+	// SVG is the board drawn with every annotated cell highlighted.
+	SVG string `json:"svg"`
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	case r.URL.Path == "/games":
+		h.list(w, r)
+	case strings.HasPrefix(r.URL.Path, "/games/"):
+		h.game(w, r, strings.TrimPrefix(r.URL.Path, "/games/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h Handler) list(w http.ResponseWriter, r *http.Request) {
+	result := []Summary{}
+	if h.Dir != "" {
+		err := filepath.Walk(h.Dir, func(fname string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !strings.HasSuffix(fname, api.RecordingExt) {
+				return err
+			}
+
+			rel, err := filepath.Rel(h.Dir, fname)
+			if err != nil {
+				return err
+			}
+
+			sum, err := summarize(fname)
+			if err != nil {
+				// recordings still being written or cut off are skipped
+				// rather than breaking the whole list
+				return nil
+			}
+			sum.Path = filepath.ToSlash(rel)
+			sum.Modified = info.ModTime()
+
+			result = append(result, sum)
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			ln.Error(r.Context(), err, ln.F{"action": "listing_recordings"})
+			http.Error(w, "can't list recordings", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Modified.After(result[j].Modified)
+	})
+
+	writeJSON(w, result)
+}
+
+// summarize reads the first record of a recording.
+func summarize(fname string) (Summary, error) {
+	fin, err := os.Open(fname)
+	if err != nil {
+		return Summary{}, err
+	}
+	defer fin.Close()
+
+	gz, err := gzip.NewReader(fin)
+	if err != nil {
+		return Summary{}, err
+	}
+	defer gz.Close()
+
+	var rec api.Record
+	if err := json.NewDecoder(bufio.NewReader(gz)).Decode(&rec); err != nil {
+		return Summary{}, err
+	}
+
+	sr := rec.Request
+	sum := Summary{
+		Snake:   rec.Snake,
+		GameID:  sr.Game.ID,
+		Source:  sr.Game.Source,
+		Ruleset: sr.Game.Ruleset.Name,
+		Width:   sr.Board.Width,
+		Height:  sr.Board.Height,
+	}
+
+	for _, sn := range sr.Board.Snakes {
+		sum.Snakes = append(sum.Snakes, sn.Name)
+	}
+
+	return sum, nil
+}
+
+func (h Handler) game(w http.ResponseWriter, r *http.Request, rel string) {
+	// cleaning the path as if it were absolute keeps it inside Dir
+	rel = strings.TrimPrefix(path.Clean("/"+rel), "/")
+	if h.Dir == "" || !strings.HasSuffix(rel, api.RecordingExt) {
+		http.NotFound(w, r)
+		return
+	}
+
+	records, err := api.OpenRecording(filepath.Join(h.Dir, filepath.FromSlash(rel)))
+	switch {
+	case os.IsNotExist(err):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		ln.Error(r.Context(), err, ln.F{"action": "reading_recording", "path": rel})
+		http.Error(w, "can't read recording", http.StatusInternalServerError)
+		return
+	case len(records) == 0:
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJSON(w, h.load(rel, records))
+}
+
+// load turns a recording into a Game. When a turn was recorded more than
+// once, such as the start and first move of a game, the last record wins.
+func (h Handler) load(rel string, records []api.Record) Game {
+	g := Game{
+		Path:  rel,
+		Snake: records[0].Snake,
+		You:   records[0].Request.You.ID,
+		Turns: []Turn{},
+	}
+
+	opts := render.Options{You: g.You}
+	if h.Brains != nil {
+		if ai, ok := h.Brains.Brain(g.Snake); ok {
+			opts.Colors = render.PingColors(map[string]api.AI{g.You: ai})
+		}
+	}
+	g.Colors = render.Colors(records[0].Request.Board, opts)
+
+	for _, rec := range records {
+		t := Turn{
+			Turn:        rec.Request.Turn,
+			Board:       rec.Request.Board,
+			Latency:     rec.Latency,
+			Error:       rec.Error,
+			Annotations: rec.Annotations,
+		}
+
+		// a brain that failed still moved, just not by its own choice
+		mr := rec.Response
+		if mr == nil {
+			mr = rec.Fallback
+		}
+		if mr != nil && mr.Move.Valid() {
+			t.Move = mr.Move.String()
+			t.Shout = mr.Shout
+		}
+
+		opts := render.Options{You: g.You, Colors: g.Colors}
+		for _, an := range rec.Annotations {
+			opts.Path = append(opts.Path, an.Cells...)
+		}
+
+		var sb strings.Builder
+		render.SVG(&sb, t.Board, opts)
+		t.SVG = sb.String()
+
+		if n := len(g.Turns); n > 0 && g.Turns[n-1].Turn == t.Turn {
+			g.Turns[n-1] = t
+			continue
+		}
+
+		g.Turns = append(g.Turns, t)
+	}
+
+	return g
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,98 @@
+package viewer
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Xe/bsnk/api"
+)
+
+type brain struct{}
+
+func (brain) Ping() (*api.PingResponse, error)                     { return &api.PingResponse{Color: "#123456"}, nil }
+func (brain) Start(ctx context.Context, sr api.SnakeRequest) error { return nil }
+func (brain) End(ctx context.Context, sr api.SnakeRequest) error   { return nil }
+
+func (brain) Move(ctx context.Context, sr api.SnakeRequest) (*api.MoveResponse, error) {
+	api.Annotate(ctx, "target", "food", sr.Board.Food...)
+	return &api.MoveResponse{Move: api.Up}, nil
+}
+
+func TestHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bsnk-viewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sr := api.SnakeRequest{
+		Game: api.Game{ID: "game", Source: "local", Ruleset: api.Ruleset{Name: "standard"}},
+		Board: api.Board{
+			Width:  5,
+			Height: 3,
+			Food:   []api.Coord{{X: 4, Y: 2}},
+			Snakes: []api.Snake{{ID: "me", Name: "tester", Health: 100, Body: []api.Coord{{X: 0, Y: 0}, {X: 0, Y: 0}}}},
+		},
+	}
+	sr.You = sr.Board.Snakes[0]
+
+	rec := &api.Recorder{Dir: dir}
+	ai := rec.Wrap("tester", brain{})
+	ctx := context.Background()
+	ai.Start(ctx, sr)
+	for sr.Turn = 0; sr.Turn < 3; sr.Turn++ {
+		ai.Move(ctx, sr)
+	}
+	ai.End(ctx, sr)
+
+	h := Handler{Dir: dir, Brains: api.BrainMap{"tester": brain{}}}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if w := get("/"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<html") {
+		t.Errorf("wanted the viewer page, got: %d", w.Code)
+	}
+
+	var games []Summary
+	if err := json.NewDecoder(get("/games").Body).Decode(&games); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(games) != 1 || games[0].Snake != "tester" || games[0].Source != "local" || games[0].Path != "tester/game_me.jsonl.gz" {
+		t.Fatalf("wrong games: %#v", games)
+	}
+
+	var g Game
+	if err := json.NewDecoder(get("/games/" + games[0].Path).Body).Decode(&g); err != nil {
+		t.Fatal(err)
+	}
+
+	// the start and first move are the same turn, and the end is one more
+	if len(g.Turns) != 4 {
+		t.Fatalf("wanted a turn per turn, got: %d", len(g.Turns))
+	}
+
+	if g.Colors["me"] != "#123456" {
+		t.Errorf("snakes should be in their pinged colors: %v", g.Colors)
+	}
+
+	first := g.Turns[0]
+	if first.Move != "up" || len(first.Annotations) != 1 || !strings.Contains(first.SVG, "<svg") {
+		t.Errorf("wrong first turn: %#v", first)
+	}
+
+	for _, path := range []string{"/games/../../etc/passwd", "/games/tester/nope.jsonl.gz", "/nope"} {
+		if w := get(path); w.Code != http.StatusNotFound {
+			t.Errorf("%s: wanted 404, got: %d", path, w.Code)
+		}
+	}
+}